@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"snippet.devlake.xyz/internal/models"
+)
+
+// Special expiry values of the snippet forms
+const (
+	expiresNever  = "never"
+	expiresCustom = "custom"
+)
+
+// defaultLifetime is how long new snippets are kept unless asked otherwise
+const defaultLifetime = 3 * 365 * 24 * time.Hour
+
+// expiryChoice is one of the lifetimes offered by the snippet forms
+type expiryChoice struct {
+	Value    string
+	Label    string
+	Lifetime time.Duration
+}
+
+// expiryChoices are the offered lifetimes, longest first
+var expiryChoices = []expiryChoice{
+	{"1095d", "3 Years", 1095 * 24 * time.Hour},
+	{"365d", "One Year", 365 * 24 * time.Hour},
+	{"30d", "One Month", 30 * 24 * time.Hour},
+	{"7d", "One Week", 7 * 24 * time.Hour},
+	{"1d", "One Day", 24 * time.Hour},
+	{"1h", "One Hour", time.Hour},
+	{"10m", "10 Minutes", 10 * time.Minute},
+}
+
+// layouts accepted for expiry timestamps, times without a zone are UTC.
+// The second one is what datetime-local inputs send.
+var expiryLayouts = []string{time.RFC3339, "2006-01-02T15:04"}
+
+// parseLifetime parses a lifetime such as "10m", "12h" or "7d". A bare
+// number is a number of days, the only unit the forms used to have.
+func parseLifetime(s string) (time.Duration, bool) {
+	number, unit := s, 24*time.Hour
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'm':
+			number, unit = s[:len(s)-1], time.Minute
+		case 'h':
+			number, unit = s[:len(s)-1], time.Hour
+		case 'd':
+			number = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+
+	// refuse lifetimes beyond what a time.Duration holds
+	d := time.Duration(n) * unit
+	if d/unit != time.Duration(n) {
+		return 0, false
+	}
+	return d, true
+}
+
+// formatLifetime writes d in the largest unit of parseLifetime dividing it
+func formatLifetime(d time.Duration) string {
+	plural := func(n time.Duration, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	switch {
+	case d%(24*time.Hour) == 0:
+		return plural(d/(24*time.Hour), "day")
+	case d%time.Hour == 0:
+		return plural(d/time.Hour, "hour")
+	default:
+		return plural(d/time.Minute, "minute")
+	}
+}
+
+// expiryPolicy holds the expiry times a deployment lets a user pick
+type expiryPolicy struct {
+	// maxLifetime is the longest a snippet may be kept, 0 for no limit
+	maxLifetime time.Duration
+	// allowNever permits snippets that never expire
+	allowNever bool
+}
+
+// expiryPolicy returns the expiry policy for the user making request r.
+// Snippets that never expire are only for authenticated users and only
+// when there is no max lifetime.
+func (app *application) expiryPolicy(r *http.Request) expiryPolicy {
+	return expiryPolicy{
+		maxLifetime: app.config.maxLifetime,
+		allowNever:  app.isAuthenticated(r) && app.config.maxLifetime == 0,
+	}
+}
+
+// choices returns the offered lifetimes within the max lifetime
+func (p expiryPolicy) choices() []expiryChoice {
+	var choices []expiryChoice
+	for _, c := range expiryChoices {
+		if p.maxLifetime == 0 || c.Lifetime <= p.maxLifetime {
+			choices = append(choices, c)
+		}
+	}
+	return choices
+}
+
+// defaultChoice is the preselected expiry of the create forms, the longest
+// offered lifetime up to defaultLifetime
+func (p expiryPolicy) defaultChoice() string {
+	for _, c := range p.choices() {
+		if c.Lifetime <= defaultLifetime {
+			return c.Value
+		}
+	}
+	return expiresCustom
+}
+
+// defaultExpiry returns the expiry time of new snippets created without
+// picking one
+func (p expiryPolicy) defaultExpiry(now time.Time) time.Time {
+	lifetime := defaultLifetime
+	if p.maxLifetime != 0 {
+		lifetime = min(lifetime, p.maxLifetime)
+	}
+	return now.Add(lifetime).Truncate(time.Second)
+}
+
+// expiry resolves value, a lifetime, "never" or a timestamp, to an expiry
+// time, which must be in the future and permitted by the policy
+func (p expiryPolicy) expiry(value string, now time.Time) (time.Time, error) {
+	var expires time.Time
+	if d, ok := parseLifetime(value); ok {
+		expires = now.Add(d)
+	} else if value == expiresNever {
+		switch {
+		case p.maxLifetime != 0:
+			return time.Time{}, fmt.Errorf("Snippets cannot be kept longer than %s", formatLifetime(p.maxLifetime))
+		case !p.allowNever:
+			return time.Time{}, errors.New("Only signed in users can keep snippets forever")
+		}
+		return models.Never, nil
+	} else {
+		var err error
+		for _, layout := range expiryLayouts {
+			expires, err = time.ParseInLocation(layout, value, time.UTC)
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return time.Time{}, errors.New("Expires must be a lifetime like 10m, 12h or 7d, a timestamp or never")
+		}
+	}
+
+	expires = expires.UTC().Truncate(time.Second)
+	switch {
+	case !expires.After(now):
+		return time.Time{}, errors.New("Expiry time must be in the future")
+	case p.maxLifetime != 0 && expires.After(now.Add(p.maxLifetime)):
+		return time.Time{}, fmt.Errorf("Snippets cannot be kept longer than %s", formatLifetime(p.maxLifetime))
+	case !expires.Before(models.Never):
+		return time.Time{}, errors.New("Expiry time is too far in the future")
+	}
+	return expires, nil
+}
+
+// extend returns the expiry time of a snippet expiring at current after
+// adding d, counted from now for snippets expiring soon. The result is
+// capped at the max lifetime, but never earlier than current, so snippets
+// kept longer than the max lifetime allows, e.g. since before it was
+// lowered, stay as they are.
+func (p expiryPolicy) extend(current time.Time, d time.Duration, now time.Time) time.Time {
+	if current.Before(now) {
+		current = now
+	}
+	expires := current.Add(d)
+	if p.maxLifetime != 0 && expires.After(now.Add(p.maxLifetime)) {
+		expires = now.Add(p.maxLifetime)
+	}
+	if !expires.After(current) {
+		return current
+	}
+	if !expires.Before(models.Never) {
+		return models.Never
+	}
+	return expires.UTC().Truncate(time.Second)
+}
+
+// apiExpiry is the expires field of API requests, a number of days or a
+// string understood by expiryPolicy.expiry
+type apiExpiry string
+
+func (e *apiExpiry) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		*e = apiExpiry(s)
+		return nil
+	}
+
+	var days int
+	err := json.Unmarshal(b, &days)
+	if err != nil {
+		// errors of custom unmarshalers don't get the field name added
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &typeError) {
+			typeError.Field = "expires"
+		}
+		return err
+	}
+	*e = apiExpiry(strconv.Itoa(days))
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"snippet.devlake.xyz/internal/assert"
+	"snippet.devlake.xyz/internal/models"
+)
+
+func TestParseLifetime(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"10m", 10 * time.Minute, true},
+		{"12h", 12 * time.Hour, true},
+		{"7d", 7 * 24 * time.Hour, true},
+		{"7", 7 * 24 * time.Hour, true},
+		{"0d", 0, false},
+		{"-1h", 0, false},
+		{"1w", 0, false},
+		{"h", 0, false},
+		{"", 0, false},
+		{"99999999999d", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, ok := parseLifetime(tt.input)
+			assert.Equal(t, ok, tt.wantOK)
+			assert.Equal(t, d, tt.want)
+		})
+	}
+}
+
+func TestExpiryPolicyExpiry(t *testing.T) {
+	now := time.Date(2024, 3, 17, 10, 0, 0, 0, time.UTC)
+	unlimited := expiryPolicy{allowNever: true}
+	limited := expiryPolicy{maxLifetime: 30 * 24 * time.Hour}
+
+	tests := []struct {
+		name    string
+		policy  expiryPolicy
+		value   string
+		want    time.Time
+		wantErr string
+	}{
+		{
+			name:   "Lifetime",
+			policy: unlimited,
+			value:  "90m",
+			want:   now.Add(90 * time.Minute),
+		},
+		{
+			name:   "Timestamp",
+			policy: unlimited,
+			value:  "2024-03-18T12:30:00+02:00",
+			want:   time.Date(2024, 3, 18, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:   "Datetime-local",
+			policy: unlimited,
+			value:  "2024-03-18T12:30",
+			want:   time.Date(2024, 3, 18, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name:   "Never",
+			policy: unlimited,
+			value:  "never",
+			want:   models.Never,
+		},
+		{
+			name:    "Never for anonymous users",
+			policy:  expiryPolicy{},
+			value:   "never",
+			wantErr: "Only signed in users can keep snippets forever",
+		},
+		{
+			name:    "Never with max lifetime",
+			policy:  limited,
+			value:   "never",
+			wantErr: "Snippets cannot be kept longer than 30 days",
+		},
+		{
+			name:   "Within max lifetime",
+			policy: limited,
+			value:  "30d",
+			want:   now.Add(30 * 24 * time.Hour),
+		},
+		{
+			name:    "Beyond max lifetime",
+			policy:  limited,
+			value:   "2024-04-17T10:01",
+			wantErr: "Snippets cannot be kept longer than 30 days",
+		},
+		{
+			name:    "Past",
+			policy:  unlimited,
+			value:   "2024-03-17T10:00",
+			wantErr: "Expiry time must be in the future",
+		},
+		{
+			name:    "Too far",
+			policy:  unlimited,
+			value:   "9999-12-31T23:59:59Z",
+			wantErr: "Expiry time is too far in the future",
+		},
+		{
+			name:    "Invalid",
+			policy:  unlimited,
+			value:   "tomorrow",
+			wantErr: "Expires must be a lifetime like 10m, 12h or 7d, a timestamp or never",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expires, err := tt.policy.expiry(tt.value, now)
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("got no error; want %q", tt.wantErr)
+				}
+				assert.Equal(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.Equal(t, err, nil)
+			assert.Equal(t, expires.Equal(tt.want), true)
+		})
+	}
+}
+
+func TestExpiryPolicyExtend(t *testing.T) {
+	now := time.Date(2024, 3, 17, 10, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name    string
+		policy  expiryPolicy
+		current time.Time
+		want    time.Time
+	}{
+		{
+			name:    "Adds to the current expiry",
+			current: now.Add(day),
+			want:    now.Add(8 * day),
+		},
+		{
+			name:    "Counts from now when expired",
+			current: now.Add(-day),
+			want:    now.Add(7 * day),
+		},
+		{
+			name:    "Capped at the max lifetime",
+			policy:  expiryPolicy{maxLifetime: 5 * day},
+			current: now.Add(day),
+			want:    now.Add(5 * day),
+		},
+		{
+			name:    "Beyond the max lifetime already",
+			policy:  expiryPolicy{maxLifetime: 5 * day},
+			current: now.Add(30 * day),
+			want:    now.Add(30 * day),
+		},
+		{
+			name:    "Never with a max lifetime",
+			policy:  expiryPolicy{maxLifetime: 5 * day},
+			current: models.Never,
+			want:    models.Never,
+		},
+		{
+			name:    "Not past the end of time",
+			current: models.Never.Add(-day),
+			want:    models.Never,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.extend(tt.current, 7*day, now)
+			assert.Equal(t, got.Equal(tt.want), true)
+		})
+	}
+}
+
+func TestExpiryPolicyChoices(t *testing.T) {
+	policy := expiryPolicy{maxLifetime: 7 * 24 * time.Hour}
+	assert.Equal(t, len(policy.choices()), 4)
+	assert.Equal(t, policy.defaultChoice(), "7d")
+
+	assert.Equal(t, expiryPolicy{}.defaultChoice(), "1095d")
+	assert.Equal(t, expiryPolicy{maxLifetime: time.Minute}.defaultChoice(), "custom")
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"snippet.devlake.xyz/internal/models"
+)
+
+// apiSnippet is the JSON representation of a snippet. Internal IDs and
+// owners are not exposed, snippets are addressed by their short ID.
+type apiSnippet struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	Visibility string    `json:"visibility"`
+	Language   string    `json:"language"`
+	Tags       []string  `json:"tags"`
+	Files      []apiFile `json:"files,omitempty"` // only for single snippets
+	Burn       bool      `json:"burn_after_reading"`
+	NoComments bool      `json:"comments_disabled"`
+	Protected  bool      `json:"password_protected"`
+	Kind       string    `json:"kind"`
+	URL        string    `json:"url"`
+	Created    time.Time `json:"created"`
+	Expires    time.Time `json:"expires"`
+	Never      bool      `json:"never_expires"`
+}
+
+// apiFile is a file of a snippet besides its main content
+type apiFile struct {
+	Name     string `json:"name"`
+	Language string `json:"language"`
+	Content  string `json:"content"`
+}
+
+// apiSnippetInput is the request body for creating and updating snippets
+type apiSnippetInput struct {
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	Visibility string    `json:"visibility"`
+	Language   string    `json:"language"`
+	Kind       string    `json:"kind"`
+	Tags       []string  `json:"tags"`
+	Files      []apiFile `json:"files,omitempty"` // only for single snippets
+	Burn       bool      `json:"burn_after_reading"`
+	NoComments bool      `json:"comments_disabled"`
+	Password   string    `json:"password"`
+	NoPassword bool      `json:"remove_password"`
+	Expires    apiExpiry `json:"expires"`
+}
+
+func newAPISnippet(r *http.Request, s *models.Snippet) apiSnippet {
+	var files []apiFile
+	for _, f := range s.Files {
+		files = append(files, apiFile{Name: f.Name, Language: f.Language, Content: f.Content})
+	}
+
+	return apiSnippet{
+		ID:         s.ShortID,
+		Title:      s.Title,
+		Content:    s.Content,
+		Visibility: s.Visibility,
+		Language:   s.Language,
+		Tags:       s.Tags,
+		Files:      files,
+		Burn:       s.BurnAfterReading,
+		NoComments: s.CommentsDisabled,
+		Protected:  s.Protected,
+		Kind:       s.Kind,
+		URL:        snippetURL(r, s.ShortID),
+		Created:    s.Created,
+		Expires:    s.Expires,
+		Never:      s.NeverExpires(),
+	}
+}
+
+// newAPIFileForms returns the form values of files sent to the API
+func newAPIFileForms(files []apiFile) []snippetFileForm {
+	forms := make([]snippetFileForm, len(files))
+	for i, f := range files {
+		forms[i] = snippetFileForm{Name: f.Name, Language: f.Language, Content: f.Content}
+	}
+	return forms
+}
+
+// apiVisibleSnippet is the JSON API counterpart of visibleSnippet
+func (app *application) apiVisibleSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, err := app.findVisibleSnippet(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w)
+		} else {
+			app.apiServerError(w, err)
+		}
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// apiReadableSnippet is the JSON API counterpart of readableSnippet
+func (app *application) apiReadableSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, err := app.findReadableSnippet(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w)
+		} else if errors.Is(err, errSnippetLocked) {
+			app.apiErrorResponse(w, http.StatusForbidden, err.Error())
+		} else {
+			app.apiServerError(w, err)
+		}
+		return nil, false
+	}
+
+	if app.burnsOnRead(r, snippet) {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	return snippet, true
+}
+
+// apiOwnedSnippet is the JSON API counterpart of ownedSnippet
+func (app *application) apiOwnedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, ok := app.apiVisibleSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if !app.ownsSnippet(r, snippet) {
+		app.apiClientError(w, http.StatusForbidden)
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// API Handlers
+
+func (app *application) apiSnippetList(w http.ResponseWriter, r *http.Request) {
+	q, err := readArchiveQuery(r)
+	if err != nil {
+		app.apiErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := app.snippets.Archive(q.Cursor, q.Backward, q.Limit)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	list := make([]apiSnippet, len(page.Snippets))
+	for i, s := range page.Snippets {
+		list[i] = newAPISnippet(r, s)
+		if app.locked(r, s) {
+			list[i].Content = ""
+		}
+	}
+
+	// neighbouring pages are linked as in RFC 8288
+	var links []string
+	base := "https://" + r.Host + "/api/v1/snippets"
+	if page.Next != nil {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, archiveURL(base, page.Next, false, q.Limit)))
+	}
+	if page.Prev != nil {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, archiveURL(base, page.Prev, true, q.Limit)))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	app.writeJSON(w, http.StatusOK, map[string]any{"snippets": list})
+}
+
+func (app *application) apiSnippetGet(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiReadableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, map[string]any{"snippet": newAPISnippet(r, snippet)})
+}
+
+func (app *application) apiSnippetCreate(w http.ResponseWriter, r *http.Request) {
+	// same defaults as the create form
+	input := apiSnippetInput{
+		Visibility: models.VisibilityPublic,
+		Kind:       models.KindText,
+	}
+	err := readJSON(w, r, &input)
+	if err != nil {
+		app.apiErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	form := snippetCreateForm{
+		Title:      input.Title,
+		Content:    input.Content,
+		Visibility: input.Visibility,
+		Language:   input.Language,
+		Kind:       input.Kind,
+		Tags:       strings.Join(input.Tags, ","),
+		Files:      newAPIFileForms(input.Files),
+		Burn:       input.Burn,
+		NoComments: input.NoComments,
+		Password:   input.Password,
+		NoPassword: input.NoPassword,
+		Expires:    string(input.Expires),
+	}
+	form.validate(false, app.expiryPolicy(r))
+	if !form.Valid() {
+		app.apiValidationError(w, form.Validator)
+		return
+	}
+	form.detectLanguage("")
+
+	shortID, err := app.snippets.Insert(form.input(), app.authenticatedUserID(r))
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	snippet, err := app.snippets.Get(shortID)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/snippets/"+shortID)
+	app.writeJSON(w, http.StatusCreated, map[string]any{"snippet": newAPISnippet(r, snippet)})
+}
+
+func (app *application) apiSnippetUpdate(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiOwnedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// the server can't read encrypted snippets, so it can't edit them either
+	if snippet.Encrypted() {
+		app.apiErrorResponse(w, http.StatusConflict, "encrypted snippets cannot be edited")
+		return
+	}
+
+	// omitted visibility, language, tags, files, burning, comments and expiry
+	// keep their current values, like the "Keep current" option of the edit
+	// form. Files are kept after decoding, decoding into the current ones
+	// would mix their fields with the new ones.
+	input := apiSnippetInput{
+		Visibility: snippet.Visibility,
+		Language:   snippet.Language,
+		Kind:       snippet.Kind,
+		Tags:       snippet.Tags,
+		Burn:       snippet.BurnAfterReading,
+		NoComments: snippet.CommentsDisabled,
+	}
+	err := readJSON(w, r, &input)
+	if err != nil {
+		app.apiErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	form := snippetCreateForm{
+		Title:      input.Title,
+		Content:    input.Content,
+		Visibility: input.Visibility,
+		Language:   input.Language,
+		Kind:       input.Kind,
+		Tags:       strings.Join(input.Tags, ","),
+		Files:      newAPIFileForms(input.Files),
+		Burn:       input.Burn,
+		NoComments: input.NoComments,
+		Password:   input.Password,
+		NoPassword: input.NoPassword,
+		Expires:    string(input.Expires),
+	}
+	if input.Files == nil {
+		form.Files = newFileForms(snippet.Files)
+	}
+	form.CheckField(form.Kind == snippet.Kind, "kind", "Kind cannot be changed")
+	form.validate(true, app.expiryPolicy(r))
+	if !form.Valid() {
+		app.apiValidationError(w, form.Validator)
+		return
+	}
+	form.detectLanguage("")
+
+	err = app.snippets.Update(snippet.ID, form.input())
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	snippet, err = app.snippets.Get(snippet.ShortID)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, map[string]any{"snippet": newAPISnippet(r, snippet)})
+}
+
+func (app *application) apiSnippetDelete(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiOwnedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Delete(snippet.ID)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
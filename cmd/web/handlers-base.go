@@ -1,26 +1,257 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"path"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/julienschmidt/httprouter"
+
+	"snippet.devlake.xyz/internal/diff"
+	"snippet.devlake.xyz/internal/highlight"
 	"snippet.devlake.xyz/internal/models"
 	"snippet.devlake.xyz/internal/validator"
-
-	"github.com/julienschmidt/httprouter"
 )
 
 type snippetCreateForm struct {
-	Title   string `form:"title"`
-	Content string `form:"content"`
+	Title      string `form:"title"`
+	Content    string `form:"content"`
+	Visibility string `form:"visibility"`
+	Language   string `form:"language"`
+	Tags       string `form:"tags"`
+	Burn       bool   `form:"burn"`
+	NoComments bool   `form:"disable_comments"`
+	Password   string `form:"password"`
+	NoPassword bool   `form:"remove_password"`
+	Kind       string `form:"-"` // only set through the API
 	validator.Validator
-	Expires int `form:"expires"`
+	Files     []snippetFileForm `form:"files"`
+	Expires   string            `form:"expires"`
+	ExpiresAt string            `form:"expires_at"` // used when Expires is "custom"
+	expires   time.Time
+}
+
+// snippetFileForm is a file of a snippet besides its main content
+type snippetFileForm struct {
+	Name     string `form:"name"`
+	Language string `form:"language"`
+	Content  string `form:"content"`
+}
+
+// newFileForms returns the form values of existing snippet files
+func newFileForms(files []models.File) []snippetFileForm {
+	forms := make([]snippetFileForm, len(files))
+	for i, f := range files {
+		forms[i] = snippetFileForm{Name: f.Name, Language: f.Language, Content: f.Content}
+	}
+	return forms
+}
+
+// validate checks the form values and records any field errors. Expiry
+// times must be permitted by policy, without one new snippets get the
+// default expiry time and edited snippets keep their current one.
+func (form *snippetCreateForm) validate(editing bool, policy expiryPolicy) {
+	form.CheckField(validator.NotBlank(form.Title), "title", "Title cannot be blank")
+	form.CheckField(
+		validator.MaxChars(form.Title, 100),
+		"title",
+		"Title cannot be longer than 100 characters",
+	)
+
+	form.CheckField(validator.NotBlank(form.Content), "content", "Content cannot be blank")
+	form.CheckField(
+		form.Kind == "" || validator.PermittedValue(form.Kind, models.KindText, models.KindEncrypted),
+		"kind",
+		"Kind must be text or encrypted",
+	)
+	if form.Kind == models.KindEncrypted {
+		form.CheckField(
+			validCiphertext(form.Content),
+			"content",
+			fmt.Sprintf("Content must be base64 encoded ciphertext of at most %d bytes", models.MaxEncryptedBytes),
+		)
+		form.CheckField(form.Language == "", "language", "Encrypted snippets have no language")
+		form.CheckField(len(form.Files) == 0, "files", "Encrypted snippets have no files")
+	}
+	form.CheckField(
+		validator.PermittedValue(
+			form.Visibility,
+			models.VisibilityPublic,
+			models.VisibilityUnlisted,
+			models.VisibilityPrivate,
+		),
+		"visibility",
+		"Visibility must be public, unlisted or private",
+	)
+
+	form.CheckField(
+		form.Language == "" || highlight.Supported(form.Language),
+		"language",
+		"Language is not supported",
+	)
+
+	tags := parseTags(form.Tags)
+	form.CheckField(
+		len(tags) <= models.MaxTags,
+		"tags",
+		fmt.Sprintf("No more than %d tags are allowed", models.MaxTags),
+	)
+	for _, tag := range tags {
+		form.CheckField(
+			validator.MaxChars(tag, models.MaxTagLength) && validator.MatchesRegex(tag, validator.TagRX),
+			"tags",
+			fmt.Sprintf("Tags must be at most %d characters of letters, digits, '+', '.' or '-'", models.MaxTagLength),
+		)
+	}
+
+	form.validateFiles()
+
+	if form.Password != "" {
+		form.CheckField(validator.MinChars(form.Password, 8), "password", "Password must be at least 8 characters long")
+		// bcrypt only uses the first 72 bytes
+		form.CheckField(len(form.Password) <= 72, "password", "Password cannot be longer than 72 bytes")
+	}
+
+	now := time.Now().UTC()
+	if form.Expires == "" {
+		if !editing {
+			form.expires = policy.defaultExpiry(now)
+		}
+		return
+	}
+	value := form.Expires
+	if value == expiresCustom {
+		value = form.ExpiresAt
+	}
+	expires, err := policy.expiry(value, now)
+	if err != nil {
+		form.AddFieldError("expires", err.Error())
+		return
+	}
+	form.expires = expires
+}
+
+// validateFiles drops blank files, as left behind by the add file control,
+// and checks the others. File names must differ from each other and from
+// the name the main content is downloaded as.
+func (form *snippetCreateForm) validateFiles() {
+	files := form.Files[:0]
+	for _, f := range form.Files {
+		if strings.TrimSpace(f.Name) != "" || strings.TrimSpace(f.Content) != "" {
+			files = append(files, f)
+		}
+	}
+	form.Files = files
+
+	form.CheckField(
+		len(form.Files) <= models.MaxFiles,
+		"files",
+		fmt.Sprintf("No more than %d files are allowed", models.MaxFiles),
+	)
+
+	seen := map[string]bool{downloadFilename(form.Title, ""): true}
+	for _, f := range form.Files {
+		form.CheckField(
+			validator.MaxChars(f.Name, models.MaxFileNameLength) && validator.MatchesRegex(f.Name, validator.FileNameRX),
+			"files",
+			fmt.Sprintf("File names must be at most %d characters of letters, digits, '.', '_' or '-'", models.MaxFileNameLength),
+		)
+		form.CheckField(!seen[f.Name], "files", "File names must be unique and differ from the title")
+		form.CheckField(validator.NotBlank(f.Content), "files", "Files cannot be empty")
+		form.CheckField(
+			f.Language == "" || highlight.Supported(f.Language),
+			"files",
+			"Language is not supported",
+		)
+		seen[f.Name] = true
+	}
+}
+
+// detectLanguage guesses the language from the content and the optional
+// file name when none was chosen, and the languages of files without one
+func (form *snippetCreateForm) detectLanguage(filename string) {
+	if form.Language == "" && form.Kind != models.KindEncrypted {
+		form.Language = highlight.Detect(filename, form.Content)
+	}
+	for i, f := range form.Files {
+		if f.Language == "" {
+			form.Files[i].Language = highlight.Detect(f.Name, f.Content)
+		}
+	}
+}
+
+// input returns the form values in the shape the snippet model expects.
+// Burn after reading snippets are never listed, so public ones are stored
+// as unlisted.
+func (form *snippetCreateForm) input() models.SnippetInput {
+	visibility := form.Visibility
+	if form.Burn && visibility == models.VisibilityPublic {
+		visibility = models.VisibilityUnlisted
+	}
+
+	files := make([]models.File, len(form.Files))
+	for i, f := range form.Files {
+		files[i] = models.File{Name: f.Name, Language: f.Language, Content: f.Content}
+	}
+
+	return models.SnippetInput{
+		Title:            form.Title,
+		Content:          form.Content,
+		Visibility:       visibility,
+		Language:         form.Language,
+		Kind:             form.Kind,
+		Tags:             parseTags(form.Tags),
+		Files:            files,
+		Expires:          form.expires,
+		BurnAfterReading: form.Burn,
+		CommentsDisabled: form.NoComments,
+		Password:         form.Password,
+		RemovePassword:   form.NoPassword,
+	}
+}
+
+// validCiphertext reports whether content is standard base64 encoding of
+// at least an AES-GCM nonce and tag and at most models.MaxEncryptedBytes
+func validCiphertext(content string) bool {
+	if base64.StdEncoding.DecodedLen(len(content)) > models.MaxEncryptedBytes+2 {
+		return false
+	}
+
+	b, err := base64.StdEncoding.Strict().DecodeString(content)
+	return err == nil && len(b) >= 12+16 && len(b) <= models.MaxEncryptedBytes
+}
+
+// parseTags splits a comma separated list of tags, lowercasing them and
+// dropping blanks and duplicates
+func parseTags(s string) []string {
+	tags := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(s, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
 }
 
 // Base Handlers
 
+// tagCloudSize is the number of tags shown in the home page tag cloud
+const tagCloudSize = 30
+
 func (app *application) home(w http.ResponseWriter, r *http.Request) {
 	snippets, err := app.snippets.Latest()
 	if err != nil {
@@ -28,35 +259,405 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tags, err := app.snippets.PopularTags(tagCloudSize)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
 	data := app.newTemplateData(r)
 	data.Snippets = snippets
+	data.TagCloud = newTagCloud(tags)
 
 	app.render(w, http.StatusOK, "home.tmpl.html", data)
 }
 
+func (app *application) snippetArchive(w http.ResponseWriter, r *http.Request) {
+	q, err := readArchiveQuery(r)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	page, err := app.snippets.Archive(q.Cursor, q.Backward, q.Limit)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = page.Snippets
+	data.Pagination = &pagination{}
+	if page.Prev != nil {
+		data.Pagination.Prev = archiveURL("/snippets", page.Prev, true, q.Limit)
+	}
+	if page.Next != nil {
+		data.Pagination.Next = archiveURL("/snippets", page.Next, false, q.Limit)
+	}
+
+	app.render(w, http.StatusOK, "archive.tmpl.html", data)
+}
+
 func (app *application) snippedView(w http.ResponseWriter, r *http.Request) {
-	params := httprouter.ParamsFromContext(r.Context())
+	// links to snippets created before short IDs existed use numeric IDs,
+	// permanently redirect them to the new address
+	if id := readIDParam(r); id != 0 {
+		shortID, err := app.snippets.LegacyShortID(id)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.notFound(w)
+			} else {
+				app.serverError(w, err)
+			}
+			return
+		}
 
-	id, err := strconv.Atoi(params.ByName("id"))
-	if err != nil || id < 1 {
-		app.notFound(w)
+		http.Redirect(w, r, "/snippet/view/"+shortID, http.StatusMovedPermanently)
+		return
+	}
+
+	snippet, ok := app.visibleSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+
+	if app.locked(r, snippet) {
+		data.Form = snippetUnlockForm{}
+		app.render(w, http.StatusOK, "unlock.tmpl.html", data)
+		return
+	}
+
+	// ask before showing a snippet that is deleted when read, so that link
+	// previews and accidental clicks don't burn it
+	if app.burnsOnRead(r, snippet) {
+		app.render(w, http.StatusOK, "burn.tmpl.html", data)
+		return
+	}
+
+	app.renderSnippetView(w, r, http.StatusOK, data)
+}
+
+type snippetUnlockForm struct {
+	Password string `form:"password"`
+	validator.Validator
+}
+
+// snippetUnlockPost checks the password of a protected snippet and unlocks
+// it for the rest of the session, wrong guesses are limited per snippet
+func (app *application) snippetUnlockPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.visibleSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	viewURL := "/snippet/view/" + snippet.ShortID
+	if !app.locked(r, snippet) {
+		http.Redirect(w, r, viewURL, http.StatusSeeOther)
 		return
 	}
 
-	snippet, err := app.snippets.Get(id)
+	var form snippetUnlockForm
+	err := app.decodePostForm(r, &form)
 	if err != nil {
-		if errors.Is(err, models.ErrNoRecord) {
-			app.notFound(w)
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+
+	// the guess is counted up front, slow password checks running in
+	// parallel could otherwise all get past the limit
+	if !app.unlockLimiter.Attempt(snippet.ID) {
+		form.AddNonFieldError("Too many wrong passwords, please try again later")
+		data.Form = form
+		app.render(w, http.StatusTooManyRequests, "unlock.tmpl.html", data)
+		return
+	}
+
+	err = app.snippets.CheckPassword(snippet.ID, form.Password)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			form.AddFieldError("password", "Wrong password")
+			data.Form = form
+			app.render(w, http.StatusUnprocessableEntity, "unlock.tmpl.html", data)
 		} else {
 			app.serverError(w, err)
 		}
 		return
 	}
 
+	app.unlockLimiter.Reset(snippet.ID)
+	app.unlock(r, snippet)
+
+	http.Redirect(w, r, viewURL, http.StatusSeeOther)
+}
+
+// snippetReveal shows a burn after reading snippet once the reader confirmed
+// the warning page, deleting it
+func (app *application) snippetReveal(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
 	data := app.newTemplateData(r)
 	data.Snippet = snippet
+	if app.burnsOnRead(r, snippet) {
+		data.Flash = "This snippet has now been deleted, it can't be viewed again."
+	}
 
-	app.render(w, http.StatusOK, "view.tmpl.html", data)
+	app.renderSnippetView(w, r, http.StatusOK, data)
+}
+
+// Search Handlers
+
+// searchPageSize is the number of search results, and snippets on tag
+// pages, shown per page
+const searchPageSize = 10
+
+func (app *application) search(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	page := max(readIntQuery(r, "page", 1), 1)
+
+	data := app.newTemplateData(r)
+	data.Query = query
+
+	if query != "" {
+		// fetch one extra result to know whether there is a next page
+		snippets, err := app.snippets.Search(
+			query,
+			app.authenticatedUserID(r),
+			searchPageSize+1,
+			(page-1)*searchPageSize,
+		)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		pageURL := func(page int) string {
+			return fmt.Sprintf("/search?q=%s&page=%d", url.QueryEscape(query), page)
+		}
+
+		data.Pagination = &pagination{}
+		if page > 1 {
+			data.Pagination.Prev = pageURL(page - 1)
+		}
+		if len(snippets) > searchPageSize {
+			snippets = snippets[:searchPageSize]
+			data.Pagination.Next = pageURL(page + 1)
+		}
+		data.Snippets = snippets
+	}
+
+	app.render(w, http.StatusOK, "search.tmpl.html", data)
+}
+
+func (app *application) tagView(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	tag := params.ByName("name")
+	if !validator.MaxChars(tag, models.MaxTagLength) || !validator.MatchesRegex(tag, validator.TagRX) {
+		app.notFound(w)
+		return
+	}
+	page := max(readIntQuery(r, "page", 1), 1)
+
+	// fetch one extra snippet to know whether there is a next page
+	snippets, err := app.snippets.ByTag(
+		tag,
+		app.authenticatedUserID(r),
+		searchPageSize+1,
+		(page-1)*searchPageSize,
+	)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	pageURL := func(page int) string {
+		return fmt.Sprintf("/tag/%s?page=%d", url.PathEscape(tag), page)
+	}
+
+	data := app.newTemplateData(r)
+	data.Tag = tag
+	data.Pagination = &pagination{}
+	if page > 1 {
+		data.Pagination.Prev = pageURL(page - 1)
+	}
+	if len(snippets) > searchPageSize {
+		snippets = snippets[:searchPageSize]
+		data.Pagination.Next = pageURL(page + 1)
+	}
+	data.Snippets = snippets
+
+	app.render(w, http.StatusOK, "tag.tmpl.html", data)
+}
+
+// Raw Snippet Handlers
+
+func (app *application) snippetRaw(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	writeRaw(w, snippet)
+}
+
+func (app *application) snippetDownload(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": downloadFilename(snippet.Title, snippet.ShortID),
+	}))
+	writeRaw(w, snippet)
+}
+
+// snippetDownloadZip sends the snippet content together with its files as
+// a zip archive
+func (app *application) snippetDownloadZip(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// build the archive in memory, so failures can still be reported
+	var buf bytes.Buffer
+	name := downloadFilename(snippet.Title, snippet.ShortID)
+	files := append([]models.File{{Name: name, Content: snippet.Content}}, snippet.Files...)
+
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: snippet.Created})
+		if err == nil {
+			_, err = io.WriteString(fw, f.Content)
+		}
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+	err := zw.Close()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": strings.TrimSuffix(name, path.Ext(name)) + ".zip",
+	}))
+	buf.WriteTo(w)
+}
+
+// writeRaw sends the snippet content as plain text
+func writeRaw(w http.ResponseWriter, snippet *models.Snippet) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(snippet.Content)))
+	io.WriteString(w, snippet.Content)
+}
+
+// Snippet History Handlers
+
+// revisionDiff holds two revisions of a snippet and the changes between them
+type revisionDiff struct {
+	From  *models.Revision
+	To    *models.Revision
+	Hunks []diff.Hunk
+}
+
+func (app *application) snippetHistory(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.visibleSnippet(w, r)
+	if !ok {
+		return
+	}
+	if app.locked(r, snippet) {
+		http.Redirect(w, r, "/snippet/view/"+snippet.ShortID, http.StatusSeeOther)
+		return
+	}
+	// the history would give away the content without burning it
+	if app.burnsOnRead(r, snippet) {
+		app.notFound(w)
+		return
+	}
+
+	revisions, err := app.snippets.Revisions(snippet.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revisions = revisions
+
+	app.render(w, http.StatusOK, "history.tmpl.html", data)
+}
+
+func (app *application) snippetDiff(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.visibleSnippet(w, r)
+	if !ok {
+		return
+	}
+	if app.locked(r, snippet) {
+		http.Redirect(w, r, "/snippet/view/"+snippet.ShortID, http.StatusSeeOther)
+		return
+	}
+	// the history would give away the content without burning it
+	if app.burnsOnRead(r, snippet) {
+		app.notFound(w)
+		return
+	}
+
+	revisions, err := app.snippets.Revisions(snippet.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if len(revisions) == 0 {
+		app.notFound(w)
+		return
+	}
+
+	// by default compare the latest revision with the one before it
+	to := readIntQuery(r, "to", revisions[0].Number)
+	from := readIntQuery(r, "from", to-1)
+
+	var d revisionDiff
+	for _, rev := range revisions {
+		switch rev.Number {
+		case from:
+			d.From = rev
+		case to:
+			d.To = rev
+		}
+	}
+	if d.To == nil || (d.From == nil && from != 0) {
+		app.notFound(w)
+		return
+	}
+
+	// comparing with revision 0 shows the whole first revision as added
+	oldContent := ""
+	if d.From != nil {
+		oldContent = d.From.Content
+	}
+	d.Hunks = diff.Unified(oldContent, d.To.Content, 3)
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revisions = revisions
+	data.Diff = &d
+
+	app.render(w, http.StatusOK, "diff.tmpl.html", data)
 }
 
 // Snippet Creation Handlers
@@ -64,12 +665,25 @@ func (app *application) snippedView(w http.ResponseWriter, r *http.Request) {
 func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 	data.Form = snippetCreateForm{
-		Expires: 1095,
+		Expires:    app.expiryPolicy(r).defaultChoice(),
+		Visibility: models.VisibilityPublic,
 	}
 
 	app.render(w, http.StatusOK, "create.tmpl.html", data)
 }
 
+// snippetCreateEncrypted shows the form for end-to-end encrypted snippets.
+// The browser encrypts the content and submits it to the JSON API.
+func (app *application) snippetCreateEncrypted(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = snippetCreateForm{
+		Expires:    app.expiryPolicy(r).defaultChoice(),
+		Visibility: models.VisibilityUnlisted,
+	}
+
+	app.render(w, http.StatusOK, "create-encrypted.tmpl.html", data)
+}
+
 func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request) {
 	// Parse and decode form values
 	var form snippetCreateForm
@@ -80,19 +694,7 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 	}
 
 	// validate form values
-	form.CheckField(validator.NotBlank(form.Title), "title", "Title cannot be blank")
-	form.CheckField(
-		validator.MaxChars(form.Title, 100),
-		"title",
-		"Title cannot be longer than 100 characters",
-	)
-
-	form.CheckField(validator.NotBlank(form.Content), "content", "Content cannot be blank")
-	form.CheckField(
-		validator.PermittedValue(form.Expires, 1, 7, 365, 1095),
-		"expires",
-		"Expires must be equal to 1, 7, 365, or 1095 days",
-	)
+	form.validate(false, app.expiryPolicy(r))
 
 	// if there are any validation errors re-render create snippet template
 	// with user values and validation errors
@@ -102,8 +704,9 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 		app.render(w, http.StatusUnprocessableEntity, "create.tmpl.html", data)
 		return
 	}
+	form.detectLanguage("")
 
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+	shortID, err := app.snippets.Insert(form.input(), app.authenticatedUserID(r))
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -113,7 +716,196 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created!")
 
 	// return response
-	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", shortID), http.StatusSeeOther)
+}
+
+// Snippet Editing Handlers
+
+func (app *application) snippetEdit(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+	// the server can't read encrypted snippets, so it can't edit them either
+	if snippet.Encrypted() {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Form = snippetCreateForm{
+		Title:      snippet.Title,
+		Content:    snippet.Content,
+		Visibility: snippet.Visibility,
+		Language:   snippet.Language,
+		Tags:       strings.Join(snippet.Tags, ", "),
+		Burn:       snippet.BurnAfterReading,
+		NoComments: snippet.CommentsDisabled,
+		Files:      newFileForms(snippet.Files),
+	}
+
+	app.render(w, http.StatusOK, "edit.tmpl.html", data)
+}
+
+func (app *application) snippetEditPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+	// the server can't read encrypted snippets, so it can't edit them either
+	if snippet.Encrypted() {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var form snippetCreateForm
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.validate(true, app.expiryPolicy(r))
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "edit.tmpl.html", data)
+		return
+	}
+	form.detectLanguage("")
+
+	err = app.snippets.Update(snippet.ID, form.input())
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully updated!")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+}
+
+// forkInput returns the values of a fork of snippet. The copy starts out
+// like a new snippet, without a password or burning, so copies of protected
+// and encrypted snippets are private rather than listing what their author
+// kept from others.
+func forkInput(snippet *models.Snippet, expires time.Time) models.SnippetInput {
+	visibility := snippet.Visibility
+	if snippet.Protected || snippet.Encrypted() {
+		visibility = models.VisibilityPrivate
+	}
+
+	return models.SnippetInput{
+		Title:      snippet.Title,
+		Content:    snippet.Content,
+		Visibility: visibility,
+		Language:   snippet.Language,
+		Kind:       snippet.Kind,
+		ParentID:   snippet.ID,
+		Tags:       snippet.Tags,
+		Files:      snippet.Files,
+		Expires:    expires,
+	}
+}
+
+// snippetForkPost copies a snippet the user can read into a new snippet
+// owned by them, which links back to the original
+func (app *application) snippetForkPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.visibleSnippet(w, r)
+	if !ok {
+		return
+	}
+	// forking would give away the content without burning it
+	if app.burnsOnRead(r, snippet) {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if app.locked(r, snippet) {
+		http.Redirect(w, r, "/snippet/view/"+snippet.ShortID, http.StatusSeeOther)
+		return
+	}
+
+	input := forkInput(snippet, app.expiryPolicy(r).defaultExpiry(time.Now().UTC()))
+	shortID, err := app.snippets.Insert(input, app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet forked, this copy is yours to edit!")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", shortID), http.StatusSeeOther)
+}
+
+type snippetExtendForm struct {
+	Extend string `form:"extend"`
+}
+
+// snippetExtendPost lets owners keep a snippet for longer, by one of the
+// lifetimes of the snippet forms or forever
+func (app *application) snippetExtendPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	var form snippetExtendForm
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	policy := app.expiryPolicy(r)
+	var expires time.Time
+	if d, ok := parseLifetime(form.Extend); ok {
+		expires = policy.extend(snippet.Expires, d, time.Now().UTC())
+	} else if form.Extend == expiresNever && policy.allowNever {
+		expires = models.Never
+	} else {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// snippets already kept as long as allowed have nothing to update
+	if !expires.Equal(snippet.Expires) {
+		err = app.snippets.SetExpiry(snippet.ID, expires)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.notFound(w)
+			} else {
+				app.serverError(w, err)
+			}
+			return
+		}
+	}
+
+	if expires.Equal(models.Never) {
+		app.sessionManager.Put(r.Context(), "flash", "Snippet will be kept until you delete it.")
+	} else {
+		app.sessionManager.Put(r.Context(), "flash", "Snippet now expires on "+humanDate(expires)+".")
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+}
+
+func (app *application) snippetDeletePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Delete(snippet.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully deleted!")
+
+	http.Redirect(w, r, "/user/snippets", http.StatusSeeOther)
 }
 
 // Custom handler for testing purposes
@@ -2,13 +2,20 @@ package main
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"snippet.devlake.xyz/internal/assert"
+	"snippet.devlake.xyz/internal/models"
 )
 
 func TestPing(t *testing.T) {
@@ -49,3 +56,245 @@ func TestPing(t *testing.T) {
 	bytes.TrimSpace(body)
 	assert.Equal(t, string(body), "OK")
 }
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "Empty",
+			input: "",
+			want:  "",
+		},
+		{
+			name:  "Spaces and blanks",
+			input: " go , , sql ,",
+			want:  "go|sql",
+		},
+		{
+			name:  "Lowercased",
+			input: "Go, C++",
+			want:  "go|c++",
+		},
+		{
+			name:  "Duplicates",
+			input: "go, GO, sql, go",
+			want:  "go|sql",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, strings.Join(parseTags(tt.input), "|"), tt.want)
+		})
+	}
+}
+
+func TestValidCiphertext(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{
+			name:    "Valid",
+			content: base64.StdEncoding.EncodeToString(make([]byte, 60)),
+			want:    true,
+		},
+		{
+			name:    "Not base64",
+			content: "hello world",
+			want:    false,
+		},
+		{
+			name:    "URL encoding",
+			content: strings.Repeat("-_", 30),
+			want:    false,
+		},
+		{
+			name:    "Shorter than nonce and tag",
+			content: base64.StdEncoding.EncodeToString(make([]byte, 27)),
+			want:    false,
+		},
+		{
+			name:    "Too large",
+			content: base64.StdEncoding.EncodeToString(make([]byte, models.MaxEncryptedBytes+1)),
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, validCiphertext(tt.content), tt.want)
+		})
+	}
+}
+
+func TestLargestCiphertext(t *testing.T) {
+	app := newTestApplication(t)
+	token := newTestToken(t, app)
+
+	ts := httptest.NewTLSServer(app.routes())
+	defer ts.Close()
+
+	ciphertext := make([]byte, models.MaxEncryptedBytes)
+	_, err := rand.Read(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := base64.StdEncoding.EncodeToString(ciphertext)
+
+	body, err := json.Marshal(map[string]string{
+		"title":   "Secret",
+		"content": content,
+		"kind":    models.KindEncrypted,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method, url string, body io.Reader, want int) apiSnippet {
+		r, err := http.NewRequest(method, ts.URL+url, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+		r.Header.Set("Content-Type", "application/json")
+		rs, err := ts.Client().Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rs.Body.Close()
+		assert.Equal(t, rs.StatusCode, want)
+
+		var envelope struct {
+			Snippet apiSnippet `json:"snippet"`
+		}
+		err = json.NewDecoder(rs.Body).Decode(&envelope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return envelope.Snippet
+	}
+
+	// the largest accepted ciphertext is stored and comes back unchanged
+	created := do(http.MethodPost, "/api/v1/snippets", bytes.NewReader(body), http.StatusCreated)
+	read := do(http.MethodGet, "/api/v1/snippets/"+created.ID, nil, http.StatusOK)
+
+	assert.Equal(t, read.Kind, models.KindEncrypted)
+	assert.Equal(t, read.Content == content, true)
+}
+
+func TestValidateFiles(t *testing.T) {
+	tooMany := make([]snippetFileForm, models.MaxFiles+1)
+	for i := range tooMany {
+		tooMany[i] = snippetFileForm{Name: fmt.Sprintf("%d.txt", i), Content: "x"}
+	}
+
+	tests := []struct {
+		name      string
+		files     []snippetFileForm
+		wantFiles int
+		wantErr   string
+	}{
+		{
+			name:      "Valid",
+			files:     []snippetFileForm{{Name: "main.go", Content: "package main"}, {Name: "Makefile", Content: "all:"}},
+			wantFiles: 2,
+		},
+		{
+			name:      "Blank files are dropped",
+			files:     []snippetFileForm{{}, {Name: "main.go", Content: "package main"}, {Content: "  "}},
+			wantFiles: 1,
+		},
+		{
+			name:      "Directory",
+			files:     []snippetFileForm{{Name: "cmd/main.go", Content: "package main"}},
+			wantFiles: 1,
+			wantErr:   "File names must be at most 100 characters of letters, digits, '.', '_' or '-'",
+		},
+		{
+			name:      "Hidden file",
+			files:     []snippetFileForm{{Name: ".env", Content: "A=1"}},
+			wantFiles: 1,
+			wantErr:   "File names must be at most 100 characters of letters, digits, '.', '_' or '-'",
+		},
+		{
+			name:      "Duplicate",
+			files:     []snippetFileForm{{Name: "a.txt", Content: "a"}, {Name: "a.txt", Content: "b"}},
+			wantFiles: 2,
+			wantErr:   "File names must be unique and differ from the title",
+		},
+		{
+			name:      "Same as the main file",
+			files:     []snippetFileForm{{Name: "Notes.txt", Content: "a"}},
+			wantFiles: 1,
+			wantErr:   "File names must be unique and differ from the title",
+		},
+		{
+			name:      "Empty",
+			files:     []snippetFileForm{{Name: "a.txt"}},
+			wantFiles: 1,
+			wantErr:   "Files cannot be empty",
+		},
+		{
+			name:      "Too many",
+			files:     tooMany,
+			wantFiles: models.MaxFiles + 1,
+			wantErr:   fmt.Sprintf("No more than %d files are allowed", models.MaxFiles),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := snippetCreateForm{Title: "Notes", Files: tt.files}
+			form.validateFiles()
+			assert.Equal(t, len(form.Files), tt.wantFiles)
+			assert.Equal(t, form.FieldErrors["files"], tt.wantErr)
+		})
+	}
+}
+
+func TestForkInput(t *testing.T) {
+	expires := time.Date(2024, 3, 17, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		snippet *models.Snippet
+		want    string
+	}{
+		{
+			name:    "Public",
+			snippet: &models.Snippet{Visibility: models.VisibilityPublic, Kind: models.KindText},
+			want:    models.VisibilityPublic,
+		},
+		{
+			name:    "Unlisted",
+			snippet: &models.Snippet{Visibility: models.VisibilityUnlisted, Kind: models.KindText},
+			want:    models.VisibilityUnlisted,
+		},
+		{
+			name:    "Password protected",
+			snippet: &models.Snippet{Visibility: models.VisibilityPublic, Kind: models.KindText, Protected: true},
+			want:    models.VisibilityPrivate,
+		},
+		{
+			name:    "Encrypted",
+			snippet: &models.Snippet{Visibility: models.VisibilityUnlisted, Kind: models.KindEncrypted},
+			want:    models.VisibilityPrivate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.snippet.ID = 7
+			input := forkInput(tt.snippet, expires)
+			assert.Equal(t, input.Visibility, tt.want)
+			assert.Equal(t, input.ParentID, 7)
+			assert.Equal(t, input.Password, "")
+			assert.Equal(t, input.BurnAfterReading, false)
+		})
+	}
+}
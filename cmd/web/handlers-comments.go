@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"snippet.devlake.xyz/internal/models"
+	"snippet.devlake.xyz/internal/validator"
+)
+
+type commentForm struct {
+	Content  string `form:"content"`
+	ParentID int    `form:"parent_id"`
+	validator.Validator
+}
+
+// threadComments orders the comments on a snippet for display, each comment
+// followed by its replies. comments must be oldest first, as returned by
+// CommentModelInterface.BySnippet.
+func threadComments(comments []*models.Comment) []*models.Comment {
+	replies := make(map[int][]*models.Comment)
+	for _, c := range comments {
+		replies[c.ParentID] = append(replies[c.ParentID], c)
+	}
+
+	threaded := make([]*models.Comment, 0, len(comments))
+	var walk func(parentID int)
+	walk = func(parentID int) {
+		for _, c := range replies[parentID] {
+			threaded = append(threaded, c)
+			walk(c.ID)
+		}
+	}
+	walk(0)
+
+	return threaded
+}
+
+// renderSnippetView renders the view page of data.Snippet with its comments.
+// Burn after reading snippets can't be commented on, as nobody but their
+// author gets to see them twice.
+func (app *application) renderSnippetView(w http.ResponseWriter, r *http.Request, status int, data *templateData) {
+	if data.Form == nil {
+		data.Form = commentForm{}
+	}
+
+	if !data.Snippet.BurnAfterReading {
+		comments, err := app.comments.BySnippet(data.Snippet.ID)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		data.Comments = threadComments(comments)
+	}
+
+	app.render(w, status, "view.tmpl.html", data)
+}
+
+// snippetCommentPost adds a comment to a snippet, or a reply to one of its
+// comments when a parent_id is given
+func (app *application) snippetCommentPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.visibleSnippet(w, r)
+	if !ok {
+		return
+	}
+	if snippet.BurnAfterReading {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if app.locked(r, snippet) {
+		http.Redirect(w, r, "/snippet/view/"+snippet.ShortID, http.StatusSeeOther)
+		return
+	}
+	if snippet.CommentsDisabled {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	var form commentForm
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// replies must be to a comment on the same snippet which isn't nested
+	// too deep, the reply links only offer those
+	if form.ParentID != 0 {
+		parent, err := app.comments.Get(form.ParentID)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, err)
+			return
+		}
+		if err != nil || parent.SnippetID != snippet.ID || !parent.CanReply() {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Content, models.MaxCommentLength), "content",
+		fmt.Sprintf("This field cannot be more than %d characters long", models.MaxCommentLength))
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.renderSnippetView(w, r, http.StatusUnprocessableEntity, data)
+		return
+	}
+
+	_, err = app.comments.Insert(snippet.ID, app.authenticatedUserID(r), form.ParentID, form.Content)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			// the parent was deleted in the meantime
+			app.clientError(w, http.StatusBadRequest)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Comment successfully posted!")
+
+	http.Redirect(w, r, "/snippet/view/"+snippet.ShortID, http.StatusSeeOther)
+}
+
+// commentDeletePost deletes a comment, which its author and the owner of the
+// snippet it's on may do
+func (app *application) commentDeletePost(w http.ResponseWriter, r *http.Request) {
+	id := readIDParam(r)
+	if id == 0 {
+		app.notFound(w)
+		return
+	}
+
+	comment, err := app.comments.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	snippet, err := app.snippets.Get(comment.SnippetShortID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if comment.UserID != app.authenticatedUserID(r) && !app.ownsSnippet(r, snippet) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	err = app.comments.Delete(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Comment successfully deleted!")
+
+	http.Redirect(w, r, "/snippet/view/"+snippet.ShortID, http.StatusSeeOther)
+}
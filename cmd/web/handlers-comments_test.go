@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"snippet.devlake.xyz/internal/assert"
+	"snippet.devlake.xyz/internal/models"
+)
+
+func TestThreadComments(t *testing.T) {
+	// oldest first, as returned by the model
+	comments := []*models.Comment{
+		{ID: 1},
+		{ID: 2},
+		{ID: 3, ParentID: 1, Depth: 1},
+		{ID: 4, ParentID: 2, Depth: 1},
+		{ID: 5, ParentID: 3, Depth: 2},
+		{ID: 6, ParentID: 1, Depth: 1},
+	}
+
+	threaded := threadComments(comments)
+
+	want := []int{1, 3, 5, 6, 2, 4}
+	assert.Equal(t, len(threaded), len(want))
+	for i, c := range threaded {
+		assert.Equal(t, c.ID, want[i])
+	}
+
+	assert.Equal(t, len(threadComments(nil)), 0)
+}
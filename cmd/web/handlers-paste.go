@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"snippet.devlake.xyz/internal/models"
+)
+
+// maxPasteBytes limits the size of content sent to /paste, which has to fit
+// the content column (see models.MaxContentBytes)
+const maxPasteBytes = 1_048_576
+
+// pasteParam returns the query string value for key, falling back to the
+// "X-<Key>" request header, e.g. "title" and "X-Title"
+func pasteParam(r *http.Request, key string) string {
+	if value := r.URL.Query().Get(key); value != "" {
+		return value
+	}
+	return r.Header.Get("X-" + key)
+}
+
+// pastePassword returns the password from the "X-Password" header or the
+// "password" field of a multipart form. Unlike other parameters it isn't
+// taken from the query string, which ends up in request logs.
+func pastePassword(r *http.Request) string {
+	if password := r.Header.Get("X-Password"); password != "" {
+		return password
+	}
+	if r.MultipartForm != nil {
+		if values := r.MultipartForm.Value["password"]; len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// readPaste returns the pasted content, either the whole request body or
+// the "file" part of a multipart form, and the uploaded file name if any
+func readPaste(w http.ResponseWriter, r *http.Request) (content, filename string, err error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPasteBytes)
+
+	var body io.Reader = r.Body
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		err = r.ParseMultipartForm(maxPasteBytes)
+		if err != nil {
+			return "", "", err
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return "", "", err
+		}
+		defer file.Close()
+
+		body, filename = file, header.Filename
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", "", err
+	}
+	return string(data), filename, nil
+}
+
+// Paste Handlers
+
+// snippetPaste creates a snippet from a raw request body for command line
+// clients, e.g. "curl -T file.txt -H 'Authorization: Bearer ...' .../paste".
+// Title, expiry, visibility, language, tags and burning are read from the query
+// string or X- headers, a password from the X-Password header or a multipart form.
+func (app *application) snippetPaste(w http.ResponseWriter, r *http.Request) {
+	content, filename, err := readPaste(w, r)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			http.Error(w, fmt.Sprintf("Content must not be larger than %d bytes", maxPasteBytes),
+				http.StatusRequestEntityTooLarge)
+		} else {
+			app.clientError(w, http.StatusBadRequest)
+		}
+		return
+	}
+
+	// same defaults as the create form, uploaded files are named after the file
+	form := snippetCreateForm{
+		Title:      pasteParam(r, "title"),
+		Content:    content,
+		Visibility: pasteParam(r, "visibility"),
+		Language:   pasteParam(r, "language"),
+		Tags:       pasteParam(r, "tags"),
+		Password:   pastePassword(r),
+		Expires:    pasteParam(r, "expires"),
+	}
+	if form.Title == "" {
+		form.Title = filename
+	}
+	if form.Visibility == "" {
+		form.Visibility = models.VisibilityPublic
+	}
+	if burn := pasteParam(r, "burn"); burn != "" {
+		var err error
+		form.Burn, err = strconv.ParseBool(burn)
+		form.CheckField(err == nil, "burn", "Burn must be true or false")
+	}
+
+	// refuse rather than ignore it, so nobody ends up with an unprotected snippet
+	form.CheckField(!r.URL.Query().Has("password"), "password",
+		"Password must be sent in the X-Password header, not the query string")
+	form.CheckField(utf8.ValidString(form.Content), "content", "Content must be UTF-8 text")
+	form.validate(false, app.expiryPolicy(r))
+	if !form.Valid() {
+		// report one field per line, in a stable order
+		lines := make([]string, 0, len(form.FieldErrors))
+		for field, message := range form.FieldErrors {
+			lines = append(lines, field+": "+message)
+		}
+		sort.Strings(lines)
+
+		http.Error(w, strings.Join(lines, "\n"), http.StatusUnprocessableEntity)
+		return
+	}
+	form.detectLanguage(filename)
+
+	shortID, err := app.snippets.Insert(form.input(), app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	url := snippetURL(r, shortID)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Location", url)
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintln(w, url)
+}
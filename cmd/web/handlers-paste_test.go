@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"snippet.devlake.xyz/internal/assert"
+)
+
+func TestPastePassword(t *testing.T) {
+	// the query string is never used
+	r := httptest.NewRequest(http.MethodPut, "/paste?password=secret", nil)
+	assert.Equal(t, pastePassword(r), "")
+
+	r.Header.Set("X-Password", "header")
+	assert.Equal(t, pastePassword(r), "header")
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("password", "field")
+	mw.Close()
+
+	r = httptest.NewRequest(http.MethodPost, "/paste", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	err := r.ParseMultipartForm(maxPasteBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, pastePassword(r), "field")
+}
+
+func TestPasteSizeLimit(t *testing.T) {
+	app := newTestApplication(t)
+	token := newTestToken(t, app)
+
+	ts := httptest.NewTLSServer(app.routes())
+	defer ts.Close()
+
+	paste := func(content string) *http.Response {
+		r, err := http.NewRequest(http.MethodPut, ts.URL+"/paste?title=Large", strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+		rs, err := ts.Client().Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs.Body.Close()
+		return rs
+	}
+
+	rs := paste(strings.Repeat("a", maxPasteBytes+1))
+	assert.Equal(t, rs.StatusCode, http.StatusRequestEntityTooLarge)
+
+	// the largest paste is stored and comes back unchanged
+	content := strings.Repeat("0123456789abcdef", maxPasteBytes/16)
+	rs = paste(content)
+	assert.Equal(t, rs.StatusCode, http.StatusCreated)
+
+	shortID := path.Base(rs.Header.Get("Location"))
+	rs, err := ts.Client().Get(ts.URL + "/snippet/raw/" + shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+	assert.Equal(t, len(body), maxPasteBytes)
+	assert.Equal(t, string(body) == content, true)
+}
@@ -21,6 +21,11 @@ type userLoginForm struct {
 	validator.Validator
 }
 
+type tokenCreateForm struct {
+	Name string `form:"name"`
+	validator.Validator
+}
+
 // User Handlers
 
 func (app *application) userSignup(w http.ResponseWriter, r *http.Request) {
@@ -152,3 +157,101 @@ func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
+
+func (app *application) userSnippets(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.snippets.ByUser(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+
+	app.render(w, http.StatusOK, "dashboard.tmpl.html", data)
+}
+
+// API Token Handlers
+
+func (app *application) userAccount(w http.ResponseWriter, r *http.Request) {
+	data, ok := app.accountTemplateData(w, r, tokenCreateForm{})
+	if !ok {
+		return
+	}
+	// a newly created token is only ever shown once
+	data.NewToken = app.sessionManager.PopString(r.Context(), "newAPIToken")
+
+	app.render(w, http.StatusOK, "account.tmpl.html", data)
+}
+
+// accountTemplateData loads the user's API tokens for the account page. If
+// that fails an error response is sent and false is returned.
+func (app *application) accountTemplateData(w http.ResponseWriter, r *http.Request, form tokenCreateForm) (*templateData, bool) {
+	tokens, err := app.tokens.ByUser(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return nil, false
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = form
+	data.Tokens = tokens
+	return data, true
+}
+
+func (app *application) userTokenCreatePost(w http.ResponseWriter, r *http.Request) {
+	var form tokenCreateForm
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Name), "name", "Name cannot be blank")
+	form.CheckField(
+		validator.MaxChars(form.Name, 100),
+		"name",
+		"Name cannot be longer than 100 characters",
+	)
+
+	if !form.Valid() {
+		data, ok := app.accountTemplateData(w, r, form)
+		if ok {
+			app.render(w, http.StatusUnprocessableEntity, "account.tmpl.html", data)
+		}
+		return
+	}
+
+	token, err := app.tokens.Insert(app.authenticatedUserID(r), form.Name)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "newAPIToken", token)
+	app.sessionManager.Put(r.Context(), "flash", "API token successfully created!")
+
+	http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+}
+
+func (app *application) userTokenRevokePost(w http.ResponseWriter, r *http.Request) {
+	id := readIDParam(r)
+	if id == 0 {
+		app.notFound(w)
+		return
+	}
+
+	err := app.tokens.Revoke(id, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "API token successfully revoked!")
+
+	http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"snippet.devlake.xyz/internal/validator"
+)
+
+// maxJSONBytes limits the size of JSON request bodies
+const maxJSONBytes = 1_048_576
+
+// apiError is the body of every API error response. Validation failures
+// also carry the field errors in the same shape as the HTML forms use.
+type apiError struct {
+	Error string `json:"error"`
+	validator.Validator
+}
+
+// isAPIRequest reports whether the request is for the JSON API
+func isAPIRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/")
+}
+
+// writeJSON encodes data as the JSON response body with the given status
+func (app *application) writeJSON(w http.ResponseWriter, status int, data any) {
+	js, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	js = append(js, '\n')
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
+
+// readJSON decodes a single JSON object from the request body into dst. The
+// returned errors are meant to be shown to the client.
+func readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	// only accept JSON, which also keeps plain HTML forms on other sites
+	// from posting to the API with the user's session cookie
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return errors.New("Content-Type must be application/json")
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONBytes)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(dst)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		var typeError *json.UnmarshalTypeError
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &syntaxError):
+			return fmt.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return errors.New("body contains badly-formed JSON")
+		case errors.As(err, &typeError):
+			if typeError.Field != "" {
+				return fmt.Errorf("body contains incorrect JSON type for field %q", typeError.Field)
+			}
+			return fmt.Errorf("body contains incorrect JSON type (at character %d)", typeError.Offset)
+		case errors.Is(err, io.EOF):
+			return errors.New("body must not be empty")
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			return fmt.Errorf("body contains unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+		case errors.As(err, &maxBytesError):
+			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		default:
+			return err
+		}
+	}
+
+	// the body must hold exactly one JSON value
+	if dec.Decode(&struct{}{}) != io.EOF {
+		return errors.New("body must only contain a single JSON value")
+	}
+
+	return nil
+}
+
+// apiErrorResponse sends a JSON error with the given status and message
+func (app *application) apiErrorResponse(w http.ResponseWriter, status int, message string) {
+	app.writeJSON(w, status, apiError{Error: message})
+}
+
+// apiServerError logs err and sends a generic JSON 500 response
+func (app *application) apiServerError(w http.ResponseWriter, err error) {
+	app.errorLog.Output(2, err.Error())
+
+	// don't use writeJSON, which would call back here if encoding failed
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, "{\n\t\"error\": %q\n}\n", http.StatusText(http.StatusInternalServerError))
+}
+
+func (app *application) apiClientError(w http.ResponseWriter, status int) {
+	app.apiErrorResponse(w, status, http.StatusText(status))
+}
+
+func (app *application) apiNotFound(w http.ResponseWriter) {
+	app.apiClientError(w, http.StatusNotFound)
+}
+
+// invalidTokenResponse rejects a request carrying a malformed or unknown API
+// token, with a JSON body for API requests and plain text otherwise
+func (app *application) invalidTokenResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	if isAPIRequest(r) {
+		app.apiErrorResponse(w, http.StatusUnauthorized, "invalid or revoked API token")
+		return
+	}
+	http.Error(w, "Invalid or revoked API token", http.StatusUnauthorized)
+}
+
+// apiValidationError sends the field errors recorded by v with a 422 status
+func (app *application) apiValidationError(w http.ResponseWriter, v validator.Validator) {
+	app.writeJSON(w, http.StatusUnprocessableEntity, apiError{Error: "validation failed", Validator: v})
+}
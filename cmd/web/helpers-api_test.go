@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"snippet.devlake.xyz/internal/assert"
+)
+
+func TestReadJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantErr     string
+	}{
+		{
+			name:        "Valid",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"title": "Hello", "expires": 7}`,
+		},
+		{
+			name:        "Expiry string",
+			contentType: "application/json",
+			body:        `{"title": "Hello", "expires": "7"}`,
+		},
+		{
+			name:        "Form content type",
+			contentType: "application/x-www-form-urlencoded",
+			body:        `{"title": "Hello"}`,
+			wantErr:     "Content-Type must be application/json",
+		},
+		{
+			name:        "Empty body",
+			contentType: "application/json",
+			wantErr:     "body must not be empty",
+		},
+		{
+			name:        "Badly-formed",
+			contentType: "application/json",
+			body:        `{"title": "Hello",}`,
+			wantErr:     "body contains badly-formed JSON (at character 19)",
+		},
+		{
+			name:        "Wrong type",
+			contentType: "application/json",
+			body:        `{"expires": true}`,
+			wantErr:     `body contains incorrect JSON type for field "expires"`,
+		},
+		{
+			name:        "Unknown field",
+			contentType: "application/json",
+			body:        `{"tittle": "Hello"}`,
+			wantErr:     `body contains unknown field "tittle"`,
+		},
+		{
+			name:        "Multiple values",
+			contentType: "application/json",
+			body:        `{"title": "Hello"} {}`,
+			wantErr:     "body must only contain a single JSON value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/snippets", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+
+			var dst apiSnippetInput
+			err := readJSON(httptest.NewRecorder(), r, &dst)
+
+			if tt.wantErr == "" {
+				assert.Equal(t, err, nil)
+				assert.Equal(t, dst.Title, "Hello")
+				assert.Equal(t, dst.Expires, apiExpiry("7"))
+				return
+			}
+			if err == nil {
+				t.Fatalf("got no error; want %q", tt.wantErr)
+			}
+			assert.Equal(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -2,12 +2,22 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"snippet.devlake.xyz/internal/models"
 
 	"github.com/go-playground/form/v4"
+	"github.com/julienschmidt/httprouter"
 )
 
 func (app *application) decodePostForm(r *http.Request, dst any) error {
@@ -39,6 +49,265 @@ func (app *application) isAuthenticated(r *http.Request) bool {
 	return isAuthenticated
 }
 
+// authenticatedUserID returns the ID of the user authenticated by session
+// or API token, or 0
+func (app *application) authenticatedUserID(r *http.Request) int {
+	if !app.isAuthenticated(r) {
+		return 0
+	}
+	id, _ := r.Context().Value(authenticatedUserIDContextKey).(int)
+	return id
+}
+
+// withAuthenticatedUser returns a copy of r marking the user with the given ID as authenticated
+func withAuthenticatedUser(r *http.Request, id int) *http.Request {
+	ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+	ctx = context.WithValue(ctx, authenticatedUserIDContextKey, id)
+	return r.WithContext(ctx)
+}
+
+// readIDParam parses the ":id" route parameter, returning 0 if it isn't a valid ID
+func readIDParam(r *http.Request) int {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		return 0
+	}
+	return id
+}
+
+// readIntQuery returns the integer query string value for key, or def if it
+// is missing or not a valid integer
+func readIntQuery(r *http.Request, key string, def int) int {
+	value, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// archiveQuery is the position and page size of an archive listing
+type archiveQuery struct {
+	Cursor   *models.Cursor
+	Backward bool
+	Limit    int
+}
+
+// readArchiveQuery reads the "after" or "before" cursor and the "limit" page
+// size of an archive listing. The returned errors are meant for the client.
+func readArchiveQuery(r *http.Request) (archiveQuery, error) {
+	q := archiveQuery{Limit: models.DefaultArchiveLimit}
+	values := r.URL.Query()
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > models.MaxArchiveLimit {
+			return q, fmt.Errorf("limit must be between 1 and %d", models.MaxArchiveLimit)
+		}
+		q.Limit = n
+	}
+
+	after, before := values.Get("after"), values.Get("before")
+	if after != "" && before != "" {
+		return q, errors.New("after and before cannot be combined")
+	}
+
+	var err error
+	switch {
+	case after != "":
+		q.Cursor, err = models.ParseCursor(after)
+	case before != "":
+		q.Cursor, err = models.ParseCursor(before)
+		q.Backward = true
+	}
+	if err != nil {
+		return q, errors.New("invalid cursor")
+	}
+
+	return q, nil
+}
+
+// archiveURL returns the address of the archive page at path next to cursor,
+// keeping non-default page sizes
+func archiveURL(path string, cursor *models.Cursor, backward bool, limit int) string {
+	values := url.Values{}
+	if backward {
+		values.Set("before", cursor.String())
+	} else {
+		values.Set("after", cursor.String())
+	}
+	if limit != models.DefaultArchiveLimit {
+		values.Set("limit", strconv.Itoa(limit))
+	}
+	return path + "?" + values.Encode()
+}
+
+// snippetURL returns the absolute address of the snippet view page
+func snippetURL(r *http.Request, shortID string) string {
+	return "https://" + r.Host + "/snippet/view/" + shortID
+}
+
+// downloadFilename turns a snippet title into a safe file name, keeping
+// letters, digits, dots, dashes and underscores. Titles without an
+// extension get ".txt", titles with nothing usable fall back to the short ID.
+func downloadFilename(title, shortID string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range title {
+		switch {
+		case c < utf8.RuneSelf && (unicode.IsLetter(c) || unicode.IsDigit(c) || c == '.' || c == '_' || c == '-'):
+			b.WriteRune(c)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	name := strings.Trim(b.String(), "-.")
+	if name == "" {
+		name = "snippet-" + shortID
+	}
+	if !strings.Contains(name, ".") {
+		name += ".txt"
+	}
+	return name
+}
+
+// findVisibleSnippet loads the snippet with the short ID from the ":id" route
+// parameter. Snippets that don't exist and private snippets of other users
+// are both reported as models.ErrNoRecord, so their existence isn't revealed.
+func (app *application) findVisibleSnippet(r *http.Request) (*models.Snippet, error) {
+	shortID := httprouter.ParamsFromContext(r.Context()).ByName("id")
+	if !models.ValidShortID(shortID) {
+		return nil, models.ErrNoRecord
+	}
+
+	snippet, err := app.snippets.Get(shortID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !snippet.VisibleTo(app.authenticatedUserID(r)) {
+		return nil, models.ErrNoRecord
+	}
+
+	return snippet, nil
+}
+
+// ownsSnippet reports whether the snippet belongs to the logged in user
+func (app *application) ownsSnippet(r *http.Request, snippet *models.Snippet) bool {
+	return snippet.UserID != 0 && snippet.UserID == app.authenticatedUserID(r)
+}
+
+// errSnippetLocked is returned by findReadableSnippet for password protected
+// snippets the user hasn't unlocked
+var errSnippetLocked = errors.New("snippet is password protected")
+
+// findReadableSnippet is like findVisibleSnippet but loads the snippet for
+// showing its content, burn after reading snippets are deleted in the process
+// unless the current user is their author
+func (app *application) findReadableSnippet(r *http.Request) (*models.Snippet, error) {
+	snippet, err := app.findVisibleSnippet(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// check before reading, which may burn the snippet
+	if app.locked(r, snippet) {
+		return nil, errSnippetLocked
+	}
+
+	return app.snippets.Read(snippet.ShortID, app.authenticatedUserID(r))
+}
+
+// unlockLifetime is how long a protected snippet stays unlocked in the
+// session after entering its password
+const unlockLifetime = 30 * time.Minute
+
+// unlockKey is the session key holding when the snippet unlock runs out
+func unlockKey(snippet *models.Snippet) string {
+	return "unlocked." + snippet.ShortID
+}
+
+// unlock remembers in the session that the user entered the snippet password
+func (app *application) unlock(r *http.Request, snippet *models.Snippet) {
+	app.sessionManager.Put(r.Context(), unlockKey(snippet), time.Now().Add(unlockLifetime).Unix())
+}
+
+// locked reports whether the snippet is password protected and the current
+// user is neither its author nor has unlocked it recently
+func (app *application) locked(r *http.Request, snippet *models.Snippet) bool {
+	if !snippet.Protected || app.ownsSnippet(r, snippet) {
+		return false
+	}
+
+	until := app.sessionManager.GetInt64(r.Context(), unlockKey(snippet))
+	return time.Now().Unix() >= until
+}
+
+// burnsOnRead reports whether showing the snippet content to the current
+// user deletes the snippet
+func (app *application) burnsOnRead(r *http.Request, snippet *models.Snippet) bool {
+	return snippet.BurnAfterReading && !app.ownsSnippet(r, snippet)
+}
+
+// visibleSnippet loads the snippet with the short ID from the ":id" route parameter and makes
+// sure the current user may view it. If not, an error response is sent and
+// false is returned.
+func (app *application) visibleSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, err := app.findVisibleSnippet(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// readableSnippet is the findReadableSnippet counterpart of visibleSnippet
+func (app *application) readableSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, err := app.findReadableSnippet(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else if errors.Is(err, errSnippetLocked) {
+			// the view page asks for the password
+			http.Redirect(w, r, "/snippet/view/"+httprouter.ParamsFromContext(r.Context()).ByName("id"), http.StatusSeeOther)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	if app.burnsOnRead(r, snippet) {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	return snippet, true
+}
+
+// ownedSnippet loads the snippet with the short ID from the ":id" route parameter and makes sure
+// it belongs to the logged in user. If not, an error response is sent and
+// false is returned.
+func (app *application) ownedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, ok := app.visibleSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if !app.ownsSnippet(r, snippet) {
+		app.clientError(w, http.StatusForbidden)
+		return nil, false
+	}
+
+	return snippet, true
+}
+
 func (app *application) serverError(w http.ResponseWriter, err error) {
 	trace := fmt.Sprintf("%s\n%s", err.Error(), debug.Stack())
 	app.errorLog.Output(2, trace)
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"snippet.devlake.xyz/internal/assert"
+	"snippet.devlake.xyz/internal/models"
+)
+
+func TestDownloadFilename(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{
+			name:  "Plain title",
+			title: "hello",
+			want:  "hello.txt",
+		},
+		{
+			name:  "With extension",
+			title: "install.sh",
+			want:  "install.sh",
+		},
+		{
+			name:  "Spaces and symbols",
+			title: "My  first/snippet!",
+			want:  "My-first-snippet.txt",
+		},
+		{
+			name:  "Header injection",
+			title: "a\"; filename=evil.exe\r\n",
+			want:  "a-filename-evil.exe",
+		},
+		{
+			name:  "Non-ASCII",
+			title: "Zürich notes",
+			want:  "Z-rich-notes.txt",
+		},
+		{
+			name:  "Nothing usable",
+			title: "../",
+			want:  "snippet-Ab3dE6gH9j.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, downloadFilename(tt.title, "Ab3dE6gH9j"), tt.want)
+		})
+	}
+}
+
+func TestReadArchiveQuery(t *testing.T) {
+	cursor := models.Cursor{Created: time.Date(2024, 3, 17, 10, 15, 0, 0, time.UTC), ID: 42}
+
+	tests := []struct {
+		name     string
+		query    string
+		backward bool
+		limit    int
+		wantID   int
+		wantErr  string
+	}{
+		{
+			name:  "First page",
+			query: "",
+			limit: models.DefaultArchiveLimit,
+		},
+		{
+			name:   "After",
+			query:  "after=" + cursor.String() + "&limit=5",
+			limit:  5,
+			wantID: 42,
+		},
+		{
+			name:     "Before",
+			query:    "before=" + cursor.String(),
+			backward: true,
+			limit:    models.DefaultArchiveLimit,
+			wantID:   42,
+		},
+		{
+			name:    "Limit too large",
+			query:   "limit=1000",
+			wantErr: "limit must be between 1 and 100",
+		},
+		{
+			name:    "Limit not a number",
+			query:   "limit=ten",
+			wantErr: "limit must be between 1 and 100",
+		},
+		{
+			name:    "Both directions",
+			query:   "after=" + cursor.String() + "&before=" + cursor.String(),
+			wantErr: "after and before cannot be combined",
+		},
+		{
+			name:    "Bad cursor",
+			query:   "after=nope",
+			wantErr: "invalid cursor",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/snippets?"+tt.query, nil)
+
+			q, err := readArchiveQuery(r)
+			if tt.wantErr != "" {
+				assert.Equal(t, err.Error(), tt.wantErr)
+				return
+			}
+
+			assert.Equal(t, err, nil)
+			assert.Equal(t, q.Backward, tt.backward)
+			assert.Equal(t, q.Limit, tt.limit)
+			if tt.wantID == 0 {
+				assert.Equal(t, q.Cursor == nil, true)
+			} else {
+				assert.Equal(t, q.Cursor.ID, tt.wantID)
+				assert.Equal(t, q.Cursor.Created.Equal(cursor.Created), true)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// guessLimiter counts password guesses per key, refusing further guesses
+// once max guesses were made within window. State is kept in memory, so
+// every instance of the application limits on its own.
+type guessLimiter struct {
+	mu        sync.Mutex
+	max       int
+	window    time.Duration
+	attempts  map[int]*guessWindow
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// guessWindow is the number of guesses for a key since start
+type guessWindow struct {
+	start time.Time
+	count int
+}
+
+func newGuessLimiter(max int, window time.Duration) *guessLimiter {
+	return &guessLimiter{
+		max:      max,
+		window:   window,
+		attempts: make(map[int]*guessWindow),
+		now:      time.Now,
+	}
+}
+
+// Attempt reserves a guess for key, reporting false if none are left. The
+// guess is counted before it's checked, so concurrent requests can't all
+// slip in before the first wrong one is recorded.
+func (l *guessLimiter) Attempt(key int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	// forget windows that ran out every once in a while, so the map
+	// doesn't keep growing
+	if now.Sub(l.lastPrune) >= l.window {
+		for k, w := range l.attempts {
+			if now.Sub(w.start) >= l.window {
+				delete(l.attempts, k)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	w, ok := l.attempts[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &guessWindow{start: now}
+		l.attempts[key] = w
+	}
+	if w.count >= l.max {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// Reset forgets the guesses for key, after a correct one
+func (l *guessLimiter) Reset(key int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, key)
+}
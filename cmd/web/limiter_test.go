@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"snippet.devlake.xyz/internal/assert"
+)
+
+func TestGuessLimiter(t *testing.T) {
+	now := time.Date(2024, 3, 17, 10, 0, 0, 0, time.UTC)
+	l := newGuessLimiter(3, 15*time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, l.Attempt(1), true)
+	}
+	assert.Equal(t, l.Attempt(1), false)
+
+	// other keys are limited separately
+	assert.Equal(t, l.Attempt(2), true)
+
+	// the window starts with the first guess
+	now = now.Add(14 * time.Minute)
+	assert.Equal(t, l.Attempt(1), false)
+
+	// a guess after the window starts counting again and drops old windows
+	now = now.Add(time.Minute)
+	assert.Equal(t, l.Attempt(1), true)
+	assert.Equal(t, l.attempts[1].count, 1)
+	assert.Equal(t, len(l.attempts), 1)
+
+	// a correct guess gives back all guesses
+	assert.Equal(t, l.Attempt(1), true)
+	assert.Equal(t, l.Attempt(1), true)
+	assert.Equal(t, l.Attempt(1), false)
+	l.Reset(1)
+	assert.Equal(t, l.Attempt(1), true)
+}
+
+func TestGuessLimiterConcurrent(t *testing.T) {
+	l := newGuessLimiter(5, 15*time.Minute)
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l.Attempt(1) {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, allowed.Load(), int32(5))
+}
@@ -1,38 +1,62 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"database/sql"
+	"errors"
 	"flag"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"snippet.devlake.xyz/internal/models"
 
 	"github.com/alexedwards/scs/mysqlstore"
+	"github.com/alexedwards/scs/sqlite3store"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form/v4"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	_ "modernc.org/sqlite"
 )
 
+// default data source names for each supported database driver
+var defaultDSNs = map[string]string{
+	"mysql":  "web:pass@tcp(localhost:32769)/snippetbox?parseTime=true",
+	"sqlite": "file:snippetbox.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate&_time_format=sqlite",
+}
+
 type config struct {
-	addr      string
-	staticDir string
-	dsn       string
+	addr            string
+	staticDir       string
+	dbDriver        string
+	dsn             string
+	autoMigrate     bool
+	shutdownTimeout time.Duration
+	maxLifetime     time.Duration
+	reaper          struct {
+		interval  time.Duration
+		batchSize int
+		grace     time.Duration
+	}
 }
 
 type application struct {
 	config         *config
 	errorLog       *log.Logger
 	infoLog        *log.Logger
-	snippets       *models.SnippetModel
-	users          *models.UserModel
+	snippets       models.SnippetModelInterface
+	users          models.UserModelInterface
+	tokens         models.TokenModelInterface
+	comments       models.CommentModelInterface
 	templateCache  map[string]*template.Template
 	formDecoder    *form.Decoder
 	sessionManager *scs.SessionManager
+	unlockLimiter  *guessLimiter
+	wg             sync.WaitGroup
 }
 
 func main() {
@@ -40,11 +64,26 @@ func main() {
 	var cfg config
 	flag.StringVar(&cfg.addr, "addr", ":4000", "HTTP network address")
 	flag.StringVar(&cfg.staticDir, "static-dir", "./ui/static", "Path to static assets")
-	flag.StringVar(
-		&cfg.dsn,
-		"dsn",
-		"web:pass@tcp(localhost:32769)/snippetbox?parseTime=true",
-		"MySQL data source name")
+	flag.StringVar(&cfg.dbDriver, "db-driver", "mysql", "Database driver (mysql or sqlite)")
+	flag.StringVar(&cfg.dsn, "dsn", "", "Data source name (defaults to a local database for the chosen driver)")
+
+	flag.BoolVar(&cfg.autoMigrate, "auto-migrate", false, "Apply pending database migrations on startup")
+	flag.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", 20*time.Second, "Time allowed for in-flight requests and workers to finish on shutdown")
+	flag.DurationVar(&cfg.reaper.interval, "reap-interval", 10*time.Minute, "How often expired snippets are purged (0 disables)")
+	flag.IntVar(&cfg.reaper.batchSize, "reap-batch", 500, "Maximum number of expired snippets deleted per query")
+	flag.DurationVar(&cfg.reaper.grace, "reap-grace", 30*24*time.Hour, "How long expired snippets with an owner are kept for their dashboard")
+	flag.Func("max-lifetime", "Longest time snippets may be kept, like 12h or 30d (default no limit)", func(s string) error {
+		if s == "0" {
+			cfg.maxLifetime = 0
+			return nil
+		}
+		d, ok := parseLifetime(s)
+		if !ok {
+			return errors.New("must be a number of minutes, hours or days like 90m, 12h or 30d")
+		}
+		cfg.maxLifetime = d
+		return nil
+	})
 
 	flag.Parse()
 
@@ -52,8 +91,16 @@ func main() {
 	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
 	errorLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
 
+	// use driver specific default DSN if none was given
+	if _, ok := defaultDSNs[cfg.dbDriver]; !ok {
+		errorLog.Fatalf("unsupported database driver %q", cfg.dbDriver)
+	}
+	if cfg.dsn == "" {
+		cfg.dsn = defaultDSNs[cfg.dbDriver]
+	}
+
 	// opening DB connection pool
-	db, err := openDB(cfg.dsn)
+	db, err := openDB(cfg.dbDriver, cfg.dsn)
 	if err != nil {
 		errorLog.Fatal(err)
 	}
@@ -61,6 +108,24 @@ func main() {
 	// closing DB connection pool when exiting main
 	defer db.Close()
 
+	// "migrate" sub-command manages the schema and exits
+	if flag.Arg(0) == "migrate" {
+		err = runMigrate(db, cfg.dbDriver, flag.Args()[1:], os.Stdout)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		return
+	}
+
+	// refuse to serve with an outdated schema
+	applied, err := checkSchema(db, cfg.dbDriver, cfg.autoMigrate)
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+	if applied > 0 {
+		infoLog.Printf("Applied %d database migration(s)", applied)
+	}
+
 	templateCache, err := newTemplateCache()
 	if err != nil {
 		errorLog.Fatal(err)
@@ -71,7 +136,6 @@ func main() {
 
 	// init session manager
 	sessionManager := scs.New()
-	sessionManager.Store = mysqlstore.New(db)
 	sessionManager.Lifetime = 4 * time.Hour
 	sessionManager.Cookie.Secure = true
 
@@ -80,11 +144,35 @@ func main() {
 		config:         &cfg,
 		errorLog:       errorLog,
 		infoLog:        infoLog,
-		snippets:       &models.SnippetModel{DB: db},
-		users:          &models.UserModel{DB: db},
 		templateCache:  templateCache,
 		formDecoder:    formDecoder,
 		sessionManager: sessionManager,
+		// five wrong passwords per snippet every 15 minutes
+		unlockLimiter: newGuessLimiter(5, 15*time.Minute),
+	}
+
+	// pick models and session store matching the database driver
+	switch cfg.dbDriver {
+	case "sqlite":
+		app.snippets = &models.SQLiteSnippetModel{DB: db}
+		app.users = &models.SQLiteUserModel{DB: db}
+		app.tokens = &models.SQLiteTokenModel{DB: db}
+		app.comments = &models.SQLiteCommentModel{DB: db}
+		sessionManager.Store = sqlite3store.New(db)
+	default:
+		app.snippets = &models.SnippetModel{DB: db}
+		app.users = &models.UserModel{DB: db}
+		app.tokens = &models.TokenModel{DB: db}
+		app.comments = &models.CommentModel{DB: db}
+		sessionManager.Store = mysqlstore.New(db)
+	}
+
+	// start background workers, they are stopped on shutdown
+	ctx, stopWorkers := context.WithCancel(context.Background())
+	if cfg.reaper.interval > 0 {
+		app.background(func() {
+			app.reapExpired(ctx, cfg.reaper.interval, cfg.reaper.grace, cfg.reaper.batchSize)
+		})
 	}
 
 	// change TLS default config
@@ -111,13 +199,31 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 	}
 
-	infoLog.Printf("Starting server on %s", cfg.addr)
-	err = server.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
-	errorLog.Fatal(err)
+	err = app.serve(server, cfg.shutdownTimeout, func() {
+		stopWorkers()
+
+		// session stores clean up expired sessions in their own goroutine
+		if store, ok := sessionManager.Store.(interface{ StopCleanup() }); ok {
+			store.StopCleanup()
+		}
+	})
+	if err != nil {
+		stopWorkers()
+		db.Close()
+		errorLog.Fatal(err)
+	}
 }
 
-func openDB(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", dsn)
+func openDB(driver, dsn string) (*sql.DB, error) {
+	if driver == "mysql" {
+		var err error
+		dsn, err = mysqlDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -126,3 +232,15 @@ func openDB(dsn string) (*sql.DB, error) {
 	}
 	return db, nil
 }
+
+// mysqlDSN sets clientFoundRows on a MySQL DSN. MySQL otherwise reports
+// only changed rows as affected, and the models take an update that
+// affected nothing for one that matched no row.
+func mysqlDSN(dsn string) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	cfg.ClientFoundRows = true
+	return cfg.FormatDSN(), nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+
+	"snippet.devlake.xyz/internal/assert"
+)
+
+func TestMySQLDSN(t *testing.T) {
+	for _, dsn := range []string{
+		defaultDSNs["mysql"],
+		"user:secret@tcp(db:3306)/snippets?parseTime=true&clientFoundRows=false",
+	} {
+		got, err := mysqlDSN(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, err := mysql.ParseDSN(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, cfg.ClientFoundRows, true)
+		assert.Equal(t, cfg.ParseTime, true)
+	}
+
+	_, err := mysqlDSN("not a dsn")
+	assert.Equal(t, err != nil, true)
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"snippet.devlake.xyz/internal/models"
 
 	"github.com/justinas/nosurf"
 )
@@ -36,8 +39,13 @@ func secureHeaders(next http.Handler) http.Handler {
 
 func (app *application) requireAuthentication(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// redirect user to login if not authenticated
+		// redirect user to login if not authenticated,
+		// API clients can't log in that way and get a 401 instead
 		if !app.isAuthenticated(r) {
+			if isAPIRequest(r) {
+				app.apiClientError(w, http.StatusUnauthorized)
+				return
+			}
 			http.Redirect(w, r, "/user/login", http.StatusSeeOther)
 			return
 		}
@@ -68,13 +76,60 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 
 		// If user exists, change request context
 		if exists {
-			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
-			r = r.WithContext(ctx)
+			r = withAuthenticatedUser(r, id)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Middleware for routes only meant for scripts, which rejects requests
+// without a valid API token
+func (app *application) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAuthenticated(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			app.clientError(w, http.StatusUnauthorized)
+			return
 		}
+
 		next.ServeHTTP(w, r)
 	})
 }
 
+// Middleware that authenticates API clients by an "Authorization: Bearer"
+// token. Requests without the header are passed on unchanged, requests
+// with an invalid token are rejected.
+func (app *application) authenticateToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the response depends on the header, even if it's missing
+		w.Header().Add("Vary", "Authorization")
+
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			app.invalidTokenResponse(w, r)
+			return
+		}
+
+		id, err := app.tokens.Authenticate(strings.TrimSpace(token))
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidCredentials) {
+				app.invalidTokenResponse(w, r)
+			} else {
+				app.apiServerError(w, err)
+			}
+			return
+		}
+
+		next.ServeHTTP(w, withAuthenticatedUser(r, id))
+	})
+}
+
 // Middleware to log incoming requests
 func (app *application) logRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
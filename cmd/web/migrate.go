@@ -0,0 +1,90 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"snippet.devlake.xyz/internal/migrations"
+)
+
+const migrateUsage = "usage: web [flags] migrate up|down|status"
+
+// runMigrate handles the "migrate" sub-command and writes a report to out
+func runMigrate(db *sql.DB, driver string, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	migrator, err := migrations.New(db, driver)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		count, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Applied %d migration(s)\n", count)
+
+	case "down":
+		mg, err := migrator.Down()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Rolled back %04d_%s\n", mg.Version, mg.Name)
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT\tSTATUS")
+		for _, s := range statuses {
+			state := "pending"
+			appliedAt := "-"
+			if s.Applied {
+				state = "applied"
+				appliedAt = humanDate(s.AppliedAt)
+			}
+			if !s.ChecksumOK {
+				state = "checksum mismatch"
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, appliedAt, state)
+		}
+		return tw.Flush()
+
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
+
+// checkSchema refuses to continue when the database schema is not up to date.
+// With autoMigrate set pending migrations are applied instead.
+func checkSchema(db *sql.DB, driver string, autoMigrate bool) (int, error) {
+	migrator, err := migrations.New(db, driver)
+	if err != nil {
+		return 0, err
+	}
+
+	if autoMigrate {
+		return migrator.Up()
+	}
+
+	pending, err := migrator.Pending()
+	if err != nil {
+		return 0, err
+	}
+	if pending > 0 {
+		return 0, fmt.Errorf("database schema is %d migration(s) behind, run \"web migrate up\" first", pending)
+	}
+	return 0, nil
+}
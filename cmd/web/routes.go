@@ -13,9 +13,20 @@ func (app *application) routes() http.Handler {
 	router := httprouter.New()
 
 	// Custom 404 handler
-	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isAPIRequest(r) {
+			app.apiNotFound(w)
+			return
+		}
 		app.notFound(w)
 	})
+	router.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isAPIRequest(r) {
+			app.apiClientError(w, http.StatusMethodNotAllowed)
+			return
+		}
+		app.clientError(w, http.StatusMethodNotAllowed)
+	})
 
 	// Setting up file server
 	fileServer := http.FileServer(http.FS(ui.Files))
@@ -26,7 +37,21 @@ func (app *application) routes() http.Handler {
 	// unprotected routes
 	dynamic := alice.New(app.sessionManager.LoadAndSave, noSurf, app.authenticate)
 	router.Handler(http.MethodGet, "/", dynamic.ThenFunc(app.home))
+	router.Handler(http.MethodGet, "/snippets", dynamic.ThenFunc(app.snippetArchive))
+	router.Handler(http.MethodGet, "/search", dynamic.ThenFunc(app.search))
+	router.Handler(http.MethodGet, "/tag/:name", dynamic.ThenFunc(app.tagView))
 	router.Handler(http.MethodGet, "/snippet/view/:id", dynamic.ThenFunc(app.snippedView))
+	router.Handler(http.MethodPost, "/snippet/view/:id", dynamic.ThenFunc(app.snippetReveal))
+	router.Handler(http.MethodPost, "/snippet/unlock/:id", dynamic.ThenFunc(app.snippetUnlockPost))
+	router.Handler(http.MethodGet, "/snippet/view/:id/history", dynamic.ThenFunc(app.snippetHistory))
+	router.Handler(http.MethodGet, "/snippet/view/:id/diff", dynamic.ThenFunc(app.snippetDiff))
+
+	// plain text content may also be fetched by scripts holding an API token
+	raw := dynamic.Append(app.authenticateToken)
+	router.Handler(http.MethodGet, "/snippet/raw/:id", raw.ThenFunc(app.snippetRaw))
+	router.Handler(http.MethodGet, "/snippet/download/:id", raw.ThenFunc(app.snippetDownload))
+	router.Handler(http.MethodGet, "/snippet/download/:id/zip", raw.ThenFunc(app.snippetDownloadZip))
+
 	router.Handler(http.MethodGet, "/user/signup", dynamic.ThenFunc(app.userSignup))
 	router.Handler(http.MethodPost, "/user/signup", dynamic.ThenFunc(app.userSignupPost))
 	router.Handler(http.MethodGet, "/user/login", dynamic.ThenFunc(app.userLogin))
@@ -36,8 +61,37 @@ func (app *application) routes() http.Handler {
 	protected := dynamic.Append(app.requireAuthentication)
 	router.Handler(http.MethodGet, "/snippet/create", protected.ThenFunc(app.snippetCreate))
 	router.Handler(http.MethodPost, "/snippet/create", protected.ThenFunc(app.snippetCreatePost))
+	router.Handler(http.MethodGet, "/snippet/create/encrypted", protected.ThenFunc(app.snippetCreateEncrypted))
+	router.Handler(http.MethodGet, "/snippet/edit/:id", protected.ThenFunc(app.snippetEdit))
+	router.Handler(http.MethodPost, "/snippet/edit/:id", protected.ThenFunc(app.snippetEditPost))
+	router.Handler(http.MethodPost, "/snippet/fork/:id", protected.ThenFunc(app.snippetForkPost))
+	router.Handler(http.MethodPost, "/snippet/extend/:id", protected.ThenFunc(app.snippetExtendPost))
+	router.Handler(http.MethodPost, "/snippet/delete/:id", protected.ThenFunc(app.snippetDeletePost))
+	router.Handler(http.MethodPost, "/snippet/comment/:id", protected.ThenFunc(app.snippetCommentPost))
+	router.Handler(http.MethodPost, "/comment/delete/:id", protected.ThenFunc(app.commentDeletePost))
+	router.Handler(http.MethodGet, "/user/snippets", protected.ThenFunc(app.userSnippets))
+	router.Handler(http.MethodGet, "/user/account", protected.ThenFunc(app.userAccount))
+	router.Handler(http.MethodPost, "/user/tokens/create", protected.ThenFunc(app.userTokenCreatePost))
+	router.Handler(http.MethodPost, "/user/tokens/revoke/:id", protected.ThenFunc(app.userTokenRevokePost))
 	router.Handler(http.MethodPost, "/user/logout", protected.ThenFunc(app.userLogoutPost))
 
+	// JSON API, authenticated by API token or the session cookie. There are no
+	// forms so nosurf is left out, readJSON only accepting JSON bodies guards
+	// cookie authenticated writes.
+	api := alice.New(app.sessionManager.LoadAndSave, app.authenticate, app.authenticateToken)
+	router.Handler(http.MethodGet, "/api/v1/snippets", api.ThenFunc(app.apiSnippetList))
+	router.Handler(http.MethodGet, "/api/v1/snippets/:id", api.ThenFunc(app.apiSnippetGet))
+
+	apiProtected := api.Append(app.requireAuthentication)
+	router.Handler(http.MethodPost, "/api/v1/snippets", apiProtected.ThenFunc(app.apiSnippetCreate))
+	router.Handler(http.MethodPut, "/api/v1/snippets/:id", apiProtected.ThenFunc(app.apiSnippetUpdate))
+	router.Handler(http.MethodDelete, "/api/v1/snippets/:id", apiProtected.ThenFunc(app.apiSnippetDelete))
+
+	// command line pastes, authenticated by API token only
+	paste := alice.New(app.authenticateToken, app.requireToken)
+	router.Handler(http.MethodPut, "/paste", paste.ThenFunc(app.snippetPaste))
+	router.Handler(http.MethodPost, "/paste", paste.ThenFunc(app.snippetPaste))
+
 	// better approach for layering middleware
 	standard := alice.New(app.recoverPanic, app.logRequest, secureHeaders)
 	return standard.Then(router)
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// serve runs the HTTPS server until it fails or the process receives SIGINT
+// or SIGTERM. On a signal in-flight requests get up to timeout to finish,
+// then stopWorkers is called and the background workers are waited for
+// within the same deadline.
+func (app *application) serve(server *http.Server, timeout time.Duration, stopWorkers func()) error {
+	shutdownError := make(chan error)
+
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+
+		app.infoLog.Printf("Shutting down server (%s)", s)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		// stop accepting connections and let active requests complete
+		err := server.Shutdown(ctx)
+		if err != nil {
+			shutdownError <- err
+			return
+		}
+
+		app.infoLog.Print("Stopping background workers")
+		stopWorkers()
+
+		done := make(chan struct{})
+		go func() {
+			app.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			shutdownError <- nil
+		case <-ctx.Done():
+			shutdownError <- fmt.Errorf("background workers did not stop: %w", ctx.Err())
+		}
+	}()
+
+	app.infoLog.Printf("Starting server on %s", server.Addr)
+	err := server.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	// ListenAndServeTLS returns straight away once Shutdown is called,
+	// wait until the shutdown is complete
+	err = <-shutdownError
+	if err != nil {
+		return err
+	}
+
+	app.infoLog.Print("Stopped server")
+	return nil
+}
@@ -5,10 +5,15 @@ import (
 	"io/fs"
 	"net/http"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/justinas/nosurf"
 
+	"snippet.devlake.xyz/internal/highlight"
+	"snippet.devlake.xyz/internal/markdown"
 	"snippet.devlake.xyz/internal/models"
 	"snippet.devlake.xyz/ui"
 )
@@ -18,22 +23,80 @@ import (
 // At the moment it only contains one field, but we'll add more
 // to it as the build progresses.
 type templateData struct {
-	Form            any
-	Snippet         *models.Snippet
-	Flash           string
-	CSRFToken       string
-	Snippets        []*models.Snippet
-	CurrentYear     int
-	IsAuthenticated bool
+	Form                any
+	Snippet             *models.Snippet
+	Flash               string
+	CSRFToken           string
+	Snippets            []*models.Snippet
+	Revisions           []*models.Revision
+	Diff                *revisionDiff
+	Comments            []*models.Comment
+	Tokens              []*models.Token
+	NewToken            string
+	Query               string
+	Pagination          *pagination
+	Tag                 string
+	TagCloud            []tagCloudEntry
+	ExpiryChoices       []expiryChoice
+	AllowNever          bool
+	CurrentYear         int
+	AuthenticatedUserID int
+	IsAuthenticated     bool
 }
 
 func (app *application) newTemplateData(r *http.Request) *templateData {
-	return &templateData{
+	data := &templateData{
 		CurrentYear:     time.Now().Year(),
 		IsAuthenticated: app.isAuthenticated(r),
 		Flash:           app.sessionManager.PopString(r.Context(), "flash"),
 		CSRFToken:       nosurf.Token(r),
 	}
+	data.AuthenticatedUserID = app.authenticatedUserID(r)
+
+	policy := app.expiryPolicy(r)
+	data.ExpiryChoices = policy.choices()
+	data.AllowNever = policy.allowNever
+	return data
+}
+
+// pagination links a list page to its neighbours, empty URLs mean
+// there is no such page
+type pagination struct {
+	Prev string
+	Next string
+}
+
+// tagCloudEntry is a tag in the home page tag cloud, Size runs from 1 for
+// the least used to 5 for the most used tags
+type tagCloudEntry struct {
+	Name  string
+	Count int
+	Size  int
+}
+
+// newTagCloud scales the tag counts to cloud sizes and orders the tags by name
+func newTagCloud(tags []*models.TagCount) []tagCloudEntry {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	low, high := tags[0].Count, tags[0].Count
+	for _, t := range tags {
+		low = min(low, t.Count)
+		high = max(high, t.Count)
+	}
+
+	cloud := make([]tagCloudEntry, len(tags))
+	for i, t := range tags {
+		size := 3
+		if high > low {
+			size = 1 + 4*(t.Count-low)/(high-low)
+		}
+		cloud[i] = tagCloudEntry{Name: t.Name, Count: t.Count, Size: size}
+	}
+	slices.SortFunc(cloud, func(a, b tagCloudEntry) int { return strings.Compare(a.Name, b.Name) })
+
+	return cloud
 }
 
 func humanDate(t time.Time) string {
@@ -44,8 +107,98 @@ func humanDate(t time.Time) string {
 	return t.UTC().Format("02 Jan 2006 at 15:04")
 }
 
+// excerptLength is the number of characters shown by excerpt
+const excerptLength = 200
+
+// excerpt returns a piece of content around the first word matching the
+// search query, with matching words wrapped in <mark> elements
+func excerpt(content, query string) template.HTML {
+	var terms [][]rune
+	for _, term := range models.SearchTerms(query) {
+		terms = append(terms, []rune(term))
+	}
+
+	// work on runes with whitespace collapsed, lowered has the same length
+	text := []rune(strings.Join(strings.Fields(content), " "))
+	lowered := make([]rune, len(text))
+	for i, r := range text {
+		lowered[i] = unicode.ToLower(r)
+	}
+
+	isWordRune := func(i int) bool {
+		return unicode.IsLetter(text[i]) || unicode.IsDigit(text[i])
+	}
+
+	// matchAt returns the end of the word starting at i if it begins
+	// with one of the terms, or -1
+	matchAt := func(i int) int {
+		if i > 0 && isWordRune(i-1) {
+			return -1
+		}
+		for _, term := range terms {
+			if len(term) <= len(lowered)-i && slices.Equal(lowered[i:i+len(term)], term) {
+				end := i + len(term)
+				for end < len(text) && isWordRune(end) {
+					end++
+				}
+				return end
+			}
+		}
+		return -1
+	}
+
+	// start a little before the first match
+	start := 0
+	for i := range text {
+		if matchAt(i) >= 0 {
+			start = max(i-excerptLength/4, 0)
+			break
+		}
+	}
+	end := min(start+excerptLength, len(text))
+	for start < end && text[start] == ' ' {
+		start++
+	}
+	for end > start && text[end-1] == ' ' {
+		end--
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		if matchEnd := matchAt(i); matchEnd >= 0 {
+			matchEnd = min(matchEnd, end)
+			b.WriteString("<mark>" + template.HTMLEscapeString(string(text[i:matchEnd])) + "</mark>")
+			i = matchEnd
+			continue
+		}
+		b.WriteString(template.HTMLEscapeString(string(text[i])))
+		i++
+	}
+	if end < len(text) {
+		b.WriteString("…")
+	}
+
+	return template.HTML(b.String())
+}
+
+// languageLabel returns the display name of a snippet language
+func languageLabel(name string) string {
+	if label, ok := highlight.Label(name); ok {
+		return label
+	}
+	return "Plain text"
+}
+
 var functions = template.FuncMap{
-	"humanDate": humanDate,
+	"humanDate":     humanDate,
+	"excerpt":       excerpt,
+	"highlight":     highlight.HTML,
+	"markdown":      markdown.HTML,
+	"languageLabel": languageLabel,
+	"languages":     func() []highlight.Language { return highlight.Languages },
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -38,3 +39,51 @@ func TestHumanDate(t *testing.T) {
 		})
 	}
 }
+
+func TestExcerpt(t *testing.T) {
+	long := strings.Repeat("word ", 100)
+
+	tests := []struct {
+		name    string
+		content string
+		query   string
+		want    string
+	}{
+		{
+			name:    "Marks words",
+			content: "Deploying the Frog\nservice",
+			query:   "deploy frog",
+			want:    "<mark>Deploying</mark> the <mark>Frog</mark> service",
+		},
+		{
+			name:    "Word starts only",
+			content: "leapfrog frogs",
+			query:   "frog",
+			want:    "leapfrog <mark>frogs</mark>",
+		},
+		{
+			name:    "Escapes content",
+			content: "<b>frog</b>",
+			query:   "frog",
+			want:    "&lt;b&gt;<mark>frog</mark>&lt;/b&gt;",
+		},
+		{
+			name:    "No match",
+			content: "short",
+			query:   "frog",
+			want:    "short",
+		},
+		{
+			name:    "Long content",
+			content: long + "frog " + long,
+			query:   "frog",
+			want:    "…" + strings.Repeat("word ", 10) + "<mark>frog</mark> " + strings.TrimSuffix(strings.Repeat("word ", 29), " ") + "…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, string(excerpt(tt.content, tt.query)), tt.want)
+		})
+	}
+}
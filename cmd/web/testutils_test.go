@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-playground/form/v4"
+
+	"snippet.devlake.xyz/internal/models"
+)
+
+// newTestApplication returns an application backed by a migrated SQLite
+// database which is removed with the test
+func newTestApplication(t *testing.T) *application {
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate&_time_format=sqlite"
+	db, err := openDB("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	_, err = checkSchema(db, "sqlite", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templateCache, err := newTemplateCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &application{
+		config:         &config{dbDriver: "sqlite"},
+		errorLog:       log.New(io.Discard, "", 0),
+		infoLog:        log.New(io.Discard, "", 0),
+		snippets:       &models.SQLiteSnippetModel{DB: db},
+		users:          &models.SQLiteUserModel{DB: db},
+		tokens:         &models.SQLiteTokenModel{DB: db},
+		comments:       &models.SQLiteCommentModel{DB: db},
+		templateCache:  templateCache,
+		formDecoder:    form.NewDecoder(),
+		sessionManager: scs.New(),
+	}
+}
+
+// newTestToken signs up a user and returns an API token of theirs
+func newTestToken(t *testing.T, app *application) string {
+	err := app.users.Insert("Alice", "alice@example.com", "pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := app.users.Authenticate("alice@example.com", "pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := app.tokens.Insert(id, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// background runs fn in a new goroutine that is tracked by the application
+// wait group, so shutdown can wait for all workers to finish
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		// workers must not take the whole application down
+		defer func() {
+			if err := recover(); err != nil {
+				app.errorLog.Printf("background worker panic: %v", err)
+			}
+		}()
+
+		fn()
+	}()
+}
+
+// reapExpired periodically hard deletes expired snippets in batches of
+// batchSize until ctx is cancelled. Snippets with an owner are kept for
+// grace after expiring, the dashboard lists them as expired meanwhile.
+func (app *application) reapExpired(ctx context.Context, interval, grace time.Duration, batchSize int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		total := 0
+		for ctx.Err() == nil {
+			n, err := app.snippets.DeleteExpired(grace, batchSize)
+			if err != nil {
+				app.errorLog.Printf("purging expired snippets: %v", err)
+				break
+			}
+			total += n
+
+			// a partial batch means nothing is left to delete
+			if n < batchSize {
+				break
+			}
+		}
+
+		if total > 0 {
+			app.infoLog.Printf("Purged %d expired snippet(s)", total)
+		}
+	}
+}
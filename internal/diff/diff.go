@@ -0,0 +1,225 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is the kind of change a diff line represents
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// String returns the name of the operation, used as CSS class suffix in templates
+func (op Op) String() string {
+	switch op {
+	case Insert:
+		return "insert"
+	case Delete:
+		return "delete"
+	default:
+		return "equal"
+	}
+}
+
+// Line is a single line of a diff. OldLine and NewLine are 1-based line
+// numbers in the old and new text, 0 when the line isn't present there.
+type Line struct {
+	Text    string
+	Op      Op
+	OldLine int
+	NewLine int
+}
+
+// Prefix returns the unified diff marker for the line
+func (l Line) Prefix() string {
+	switch l.Op {
+	case Insert:
+		return "+"
+	case Delete:
+		return "-"
+	default:
+		return " "
+	}
+}
+
+// Hunk is a group of changed lines together with their surrounding context
+type Hunk struct {
+	Lines    []Line
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+}
+
+// Header returns the unified diff range header, e.g. "@@ -1,3 +1,4 @@"
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+}
+
+// splitLines splits text into lines, treating "\r\n" the same as "\n"
+// since browsers submit textarea content with Windows line endings
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Lines returns the full line by line diff between a and b
+func Lines(a, b string) []Line {
+	return myers(splitLines(a), splitLines(b))
+}
+
+// Unified groups the changes between a and b into hunks, each with up to
+// context unchanged lines around it. Identical texts produce no hunks.
+func Unified(a, b string, context int) []Hunk {
+	lines := Lines(a, b)
+
+	var hunks []Hunk
+	hunkStart, lastChange := -1, -1
+
+	for i, line := range lines {
+		if line.Op == Equal {
+			continue
+		}
+
+		// start a new hunk unless the change is close enough to the previous one
+		if hunkStart < 0 || i-lastChange-1 > 2*context {
+			if hunkStart >= 0 {
+				hunks = append(hunks, newHunk(lines, hunkStart, min(lastChange+context, len(lines)-1)))
+			}
+			hunkStart = max(i-context, 0)
+		}
+		lastChange = i
+	}
+
+	if hunkStart >= 0 {
+		hunks = append(hunks, newHunk(lines, hunkStart, min(lastChange+context, len(lines)-1)))
+	}
+	return hunks
+}
+
+// newHunk builds the hunk covering lines[start:end+1] and works out its ranges
+func newHunk(lines []Line, start, end int) Hunk {
+	h := Hunk{Lines: lines[start : end+1]}
+
+	// count lines of each text before the hunk
+	for _, line := range lines[:start] {
+		if line.Op != Insert {
+			h.OldStart++
+		}
+		if line.Op != Delete {
+			h.NewStart++
+		}
+	}
+
+	for _, line := range h.Lines {
+		if line.Op != Insert {
+			h.OldLines++
+		}
+		if line.Op != Delete {
+			h.NewLines++
+		}
+	}
+
+	// ranges start at the first line of the hunk, empty ranges
+	// point at the line before it, following diff(1)
+	if h.OldLines > 0 {
+		h.OldStart++
+	}
+	if h.NewLines > 0 {
+		h.NewStart++
+	}
+	return h
+}
+
+// myers computes a shortest edit script between a and b using the
+// algorithm from Eugene W. Myers' "An O(ND) Difference Algorithm and
+// Its Variations".
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	limit := n + m
+
+	// v holds the furthest reaching x for each diagonal k, offset by limit+1
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+
+	// trace keeps the part of v needed to backtrack from each step d
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		snapshot := make([]int, 2*d+3)
+		copy(snapshot, v[offset-d-1:offset+d+2])
+		trace = append(trace, snapshot)
+
+		done := false
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				done = true
+				break
+			}
+		}
+		if done {
+			break
+		}
+	}
+
+	// walk back through the trace collecting lines in reverse order
+	var reversed []Line
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		snapshot := trace[d]
+		at := func(k int) int { return snapshot[k+d+1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Line{Op: Equal, Text: a[x-1], OldLine: x, NewLine: y})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, Line{Op: Insert, Text: b[y-1], NewLine: y})
+			} else {
+				reversed = append(reversed, Line{Op: Delete, Text: a[x-1], OldLine: x})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	lines := make([]Line, len(reversed))
+	for i := range reversed {
+		lines[i] = reversed[len(reversed)-1-i]
+	}
+	return lines
+}
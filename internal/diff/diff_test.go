@@ -0,0 +1,99 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"snippet.devlake.xyz/internal/assert"
+)
+
+// render formats hunks the way diff -u prints them
+func render(hunks []Hunk) string {
+	var b strings.Builder
+	for _, h := range hunks {
+		b.WriteString(h.Header() + "\n")
+		for _, l := range h.Lines {
+			b.WriteString(l.Prefix() + l.Text + "\n")
+		}
+	}
+	return b.String()
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       string
+		b       string
+		context int
+		want    string
+	}{
+		{
+			name: "Identical",
+			a:    "one\ntwo\n",
+			b:    "one\ntwo\n",
+			want: "",
+		},
+		{
+			name:    "Changed line",
+			a:       "one\ntwo\nthree\n",
+			b:       "one\n2\nthree\n",
+			context: 3,
+			want:    "@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		},
+		{
+			name:    "From empty",
+			a:       "",
+			b:       "one\ntwo",
+			context: 3,
+			want:    "@@ -0,0 +1,2 @@\n+one\n+two\n",
+		},
+		{
+			name:    "Windows line endings",
+			a:       "one\r\ntwo",
+			b:       "one\ntwo\n",
+			context: 3,
+			want:    "",
+		},
+		{
+			name:    "Separate hunks",
+			a:       "a\nb\nc\nd\ne\nf\ng\nh",
+			b:       "A\nb\nc\nd\ne\nf\ng\nH",
+			context: 1,
+			want:    "@@ -1,2 +1,2 @@\n-a\n+A\n b\n@@ -7,2 +7,2 @@\n g\n-h\n+H\n",
+		},
+		{
+			name:    "Merged hunks",
+			a:       "a\nb\nc\nd",
+			b:       "A\nb\nc\nD",
+			context: 1,
+			want:    "@@ -1,4 +1,4 @@\n-a\n+A\n b\n c\n-d\n+D\n",
+		},
+		{
+			name:    "Insertion without context",
+			a:       "a\nc",
+			b:       "a\nb\nc",
+			context: 0,
+			want:    "@@ -1,0 +2,1 @@\n+b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, render(Unified(tt.a, tt.b, tt.context)), tt.want)
+		})
+	}
+}
+
+func TestLinesNumbering(t *testing.T) {
+	lines := Lines("a\nb\nc", "b\nc\nd")
+
+	var got []string
+	for _, l := range lines {
+		got = append(got, l.Prefix()+l.Text)
+	}
+	assert.Equal(t, strings.Join(got, ","), "-a, b, c,+d")
+
+	// Unchanged lines know their position in both texts
+	assert.Equal(t, lines[1].OldLine, 2)
+	assert.Equal(t, lines[1].NewLine, 1)
+}
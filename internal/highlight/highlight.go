@@ -0,0 +1,194 @@
+// Package highlight renders source code as syntax highlighted HTML. The
+// markup only uses CSS classes, the matching styles live in main.css, so it
+// works with a Content-Security-Policy that forbids inline styles.
+package highlight
+
+import (
+	"bytes"
+	"html/template"
+	"path"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// Language is a language snippets can be highlighted as. Name is the
+// value stored with the snippet, Label is shown to users.
+type Language struct {
+	Name  string
+	Label string
+}
+
+// Languages lists the supported languages in the order they're offered on
+// the snippet forms. An empty language name means plain text.
+var Languages = []Language{
+	{"bash", "Bash"},
+	{"c", "C"},
+	{"cpp", "C++"},
+	{"csharp", "C#"},
+	{"css", "CSS"},
+	{"diff", "Diff"},
+	{"docker", "Dockerfile"},
+	{"go", "Go"},
+	{"haskell", "Haskell"},
+	{"html", "HTML"},
+	{"ini", "INI"},
+	{"java", "Java"},
+	{"javascript", "JavaScript"},
+	{"json", "JSON"},
+	{"kotlin", "Kotlin"},
+	{"lua", "Lua"},
+	{"makefile", "Makefile"},
+	{"markdown", "Markdown"},
+	{"perl", "Perl"},
+	{"php", "PHP"},
+	{"powershell", "PowerShell"},
+	{"python", "Python"},
+	{"ruby", "Ruby"},
+	{"rust", "Rust"},
+	{"sql", "SQL"},
+	{"swift", "Swift"},
+	{"toml", "TOML"},
+	{"typescript", "TypeScript"},
+	{"xml", "XML"},
+	{"yaml", "YAML"},
+}
+
+// byLexer maps chroma lexer names back to the supported language names
+var byLexer = map[string]string{}
+
+func init() {
+	for _, lang := range Languages {
+		lexer := lexers.Get(lang.Name)
+		if lexer == nil {
+			panic("highlight: no lexer for " + lang.Name)
+		}
+		byLexer[lexer.Config().Name] = lang.Name
+	}
+}
+
+// formatter writes <pre class="chroma"><code> blocks with class based markup,
+// style only decides which token types get a class. The CSS in main.css
+// was generated from the same style with formatter.WriteCSS.
+var (
+	formatter = html.New(html.WithClasses(true))
+	style     = styles.Get("github")
+)
+
+// Supported reports whether name is one of Languages
+func Supported(name string) bool {
+	_, ok := Label(name)
+	return ok
+}
+
+// Label returns the display name of a supported language
+func Label(name string) (string, bool) {
+	for _, lang := range Languages {
+		if lang.Name == name {
+			return lang.Label, true
+		}
+	}
+	return "", false
+}
+
+// Resolve maps a language name or alias as used by other tools, e.g. "js"
+// or "golang" in Markdown code fences, to a supported language name. It
+// returns "" if there is no matching supported language.
+func Resolve(alias string) string {
+	if Supported(alias) {
+		return alias
+	}
+	if lexer := lexers.Get(alias); lexer != nil {
+		return byLexer[lexer.Config().Name]
+	}
+	return ""
+}
+
+// Detect guesses the language of content, using the file name when one is
+// known. It returns "" if the language isn't recognised or not supported.
+func Detect(filename, content string) string {
+	if filename != "" {
+		if lexer := lexers.Match(filename); lexer != nil {
+			if name, ok := byLexer[lexer.Config().Name]; ok {
+				return name
+			}
+		}
+	}
+
+	if name := detectShebang(content); name != "" {
+		return name
+	}
+
+	if lexer := lexers.Analyse(content); lexer != nil {
+		if name, ok := byLexer[lexer.Config().Name]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// interpreters maps script interpreters to the language of their scripts
+var interpreters = map[string]string{
+	"sh":     "bash",
+	"bash":   "bash",
+	"zsh":    "bash",
+	"python": "python",
+	"ruby":   "ruby",
+	"perl":   "perl",
+	"node":   "javascript",
+	"php":    "php",
+	"lua":    "lua",
+	"pwsh":   "powershell",
+}
+
+// detectShebang returns the language of a script starting with a "#!" line,
+// e.g. "#!/bin/sh" or "#!/usr/bin/env python3"
+func detectShebang(content string) string {
+	line, _, _ := strings.Cut(content, "\n")
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+
+	fields := strings.Fields(line[2:])
+	if len(fields) > 1 && path.Base(fields[0]) == "env" {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// ignore versions such as python3 or python3.12
+	interpreter := strings.TrimRight(path.Base(fields[0]), "0123456789.")
+	return interpreters[interpreter]
+}
+
+// HTML returns content highlighted as language, unsupported languages are
+// rendered as plain text. Content is escaped by the formatter.
+func HTML(content, language string) (template.HTML, error) {
+	var lexer chroma.Lexer
+	if Supported(language) {
+		lexer = lexers.Get(language)
+	} else {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	// browsers submit textarea content with Windows line endings
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	iterator, err := lexer.Tokenise(nil, content)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = formatter.Format(&buf, style, iterator)
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(buf.String()), nil
+}
@@ -0,0 +1,94 @@
+package highlight
+
+import (
+	"strings"
+	"testing"
+
+	"snippet.devlake.xyz/internal/assert"
+)
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		language string
+		contains string
+	}{
+		{
+			name:     "Go keyword",
+			content:  "package main\n",
+			language: "go",
+			contains: `<span class="kn">package</span>`,
+		},
+		{
+			name:     "Escapes markup",
+			content:  "<script>alert(1)</script>",
+			language: "",
+			contains: "&lt;script&gt;",
+		},
+		{
+			name:     "Unsupported language",
+			content:  "<b>",
+			language: "cobol",
+			contains: "&lt;b&gt;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := HTML(tt.content, tt.language)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, strings.Contains(string(html), tt.contains), true)
+			assert.Equal(t, strings.HasPrefix(string(html), `<pre class="chroma">`), true)
+
+			// the Content-Security-Policy forbids inline styles
+			assert.Equal(t, strings.Contains(string(html), "style="), false)
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		want     string
+	}{
+		{
+			name:     "File extension",
+			filename: "main.go",
+			content:  "package main",
+			want:     "go",
+		},
+		{
+			name:    "Shebang",
+			content: "#!/bin/bash\necho hi\n",
+			want:    "bash",
+		},
+		{
+			name:    "Python shebang",
+			content: "#!/usr/bin/env python3\nprint('hi')\n",
+			want:    "python",
+		},
+		{
+			name:     "Unsupported file type",
+			filename: "notes.cbl",
+			content:  "hello",
+			want:     "",
+		},
+		{
+			name:    "Plain text",
+			content: "just some words",
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, Detect(tt.filename, tt.content), tt.want)
+		})
+	}
+}
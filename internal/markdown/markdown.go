@@ -0,0 +1,83 @@
+// Package markdown renders Markdown snippets to sanitized HTML. Code fences
+// are syntax highlighted the same way as code snippets.
+package markdown
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+
+	"snippet.devlake.xyz/internal/highlight"
+)
+
+// parser converts GitHub flavoured Markdown to HTML. Raw HTML in the source
+// is left out since goldmark's unsafe mode isn't enabled.
+var parser = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(
+		renderer.WithNodeRenderers(util.Prioritized(codeBlockRenderer{}, 100)),
+	),
+)
+
+// policy is applied to the rendered HTML as a second line of defence. On top
+// of the user generated content defaults it only allows the classes used
+// by the highlighter.
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[a-z0-9 ]+$`)).OnElements("pre", "code", "span")
+	return p
+}()
+
+// HTML renders source as sanitized HTML
+func HTML(source string) (template.HTML, error) {
+	var buf bytes.Buffer
+	err := parser.Convert([]byte(source), &buf)
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(policy.SanitizeReader(&buf).String()), nil
+}
+
+// codeBlockRenderer renders fenced and indented code blocks with the highlighter
+type codeBlockRenderer struct{}
+
+func (r codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderCodeBlock)
+	reg.Register(ast.KindCodeBlock, r.renderCodeBlock)
+}
+
+func (r codeBlockRenderer) renderCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	var code strings.Builder
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code.Write(line.Value(source))
+	}
+
+	// the info string names the language, e.g. "```go"
+	language := ""
+	if fenced, ok := node.(*ast.FencedCodeBlock); ok && fenced.Info != nil {
+		language = highlight.Resolve(string(fenced.Language(source)))
+	}
+
+	html, err := highlight.HTML(code.String(), language)
+	if err != nil {
+		return ast.WalkStop, err
+	}
+
+	_, err = w.WriteString(string(html))
+	return ast.WalkSkipChildren, err
+}
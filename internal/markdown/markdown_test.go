@@ -0,0 +1,74 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"snippet.devlake.xyz/internal/assert"
+)
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		contains    string
+		notContains string
+	}{
+		{
+			name:     "Heading",
+			source:   "# Title",
+			contains: "<h1>Title</h1>",
+		},
+		{
+			name:     "Table",
+			source:   "| a |\n|---|\n| b |",
+			contains: "<td>b</td>",
+		},
+		{
+			name:        "Raw HTML",
+			source:      "<script>alert(1)</script>\n\nhi <img src=x onerror=alert(1)>",
+			notContains: "<script",
+		},
+		{
+			name:        "Event handler",
+			source:      "hi <b onclick=\"alert(1)\">there</b>",
+			notContains: "onclick",
+		},
+		{
+			name:        "JavaScript link",
+			source:      "[click](javascript:alert(1))",
+			notContains: "javascript:",
+		},
+		{
+			name:     "Code fence",
+			source:   "```golang\npackage main\n```",
+			contains: `<span class="kn">package</span>`,
+		},
+		{
+			name:     "Escaped code",
+			source:   "```\n<b>\n```",
+			contains: "&lt;b&gt;",
+		},
+		{
+			name:        "Inline style",
+			source:      "```go\npackage main\n```",
+			notContains: "style=",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := HTML(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.contains != "" {
+				assert.Equal(t, strings.Contains(string(html), tt.contains), true)
+			}
+			if tt.notContains != "" {
+				assert.Equal(t, strings.Contains(string(html), tt.notContains), false)
+			}
+		})
+	}
+}
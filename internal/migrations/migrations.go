@@ -0,0 +1,353 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration files for each supported driver are embedded into the executable.
+// Files are named "<version>_<name>.up.sql" and "<version>_<name>.down.sql"
+// and are applied in version order.
+
+//go:embed "mysql" "sqlite"
+var files embed.FS
+
+var (
+	ErrChecksumMismatch  = errors.New("migrations: applied migration differs from embedded file")
+	ErrUnknownMigration  = errors.New("migrations: database has a migration unknown to this build")
+	ErrNothingToRollBack = errors.New("migrations: no applied migrations to roll back")
+)
+
+// supersededChecksums are the checksums of earlier versions of up files
+// fixed after release, per driver and version. Databases that applied one
+// of them are still considered up to date.
+var supersededChecksums = map[string]map[int][]string{
+	"mysql": {
+		// random backfilled short IDs could collide
+		7: {"f21804c22ca5017b8c46f05ed60c9b55a59abbcc06376e6e1f7a235244a61bf2"},
+	},
+	"sqlite": {
+		7: {"19b41b280a0ce479070c46225e185bc5ddd913d2028480c91b19d1c7cde32712"},
+	},
+}
+
+var fileRX = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+const createTableStmt = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	checksum CHAR(64) NOT NULL,
+	applied_at DATETIME NOT NULL
+)`
+
+type Migration struct {
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+	// Superseded are checksums of earlier versions of Up that are accepted too
+	Superseded []string
+	Version    int
+}
+
+// matches reports whether checksum is that of the migration, or of an
+// earlier version of it
+func (mg *Migration) matches(checksum string) bool {
+	return checksum == mg.Checksum || slices.Contains(mg.Superseded, checksum)
+}
+
+// Status describes a single migration and whether it has been applied
+type Status struct {
+	AppliedAt  time.Time
+	Name       string
+	Version    int
+	Applied    bool
+	ChecksumOK bool
+}
+
+type Migrator struct {
+	DB         *sql.DB
+	migrations []*Migration
+}
+
+// New loads the embedded migrations for the given database driver
+func New(db *sql.DB, driver string) (*Migrator, error) {
+	migrations, err := load(driver)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, migrations: migrations}, nil
+}
+
+func load(driver string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(files, driver)
+	if err != nil {
+		return nil, fmt.Errorf("migrations: no migrations for driver %q", driver)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		matches := fileRX.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := fs.ReadFile(files, path.Join(driver, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2], Superseded: supersededChecksums[driver][version]}
+			byVersion[version] = m
+		}
+		if m.Name != matches[2] {
+			return nil, fmt.Errorf("migrations: version %d has conflicting names %q and %q", version, m.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migrations: version %d needs both up and down files", m.Version)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Status lists every known migration together with its applied state
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		s := Status{Version: mg.Version, Name: mg.Name, ChecksumOK: true}
+		if a, ok := applied[mg.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = a.appliedAt
+			s.ChecksumOK = mg.matches(a.checksum)
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// Pending returns the number of migrations that still need to be applied
+func (m *Migrator) Pending() (int, error) {
+	applied, err := m.verify()
+	if err != nil {
+		return 0, err
+	}
+	return len(m.migrations) - len(applied), nil
+}
+
+// Up applies all pending migrations in order and returns how many were run
+func (m *Migrator) Up() (int, error) {
+	applied, err := m.verify()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, mg := range m.migrations {
+		if _, ok := applied[mg.Version]; ok {
+			continue
+		}
+
+		err = m.run(mg.Up, func(tx *sql.Tx) error {
+			stmt := `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`
+			_, err := tx.Exec(stmt, mg.Version, mg.Name, mg.Checksum, time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return count, fmt.Errorf("migrations: applying %04d_%s: %w", mg.Version, mg.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Down rolls back the most recently applied migration
+func (m *Migrator) Down() (*Migration, error) {
+	applied, err := m.verify()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mg := m.migrations[i]
+		if _, ok := applied[mg.Version]; !ok {
+			continue
+		}
+
+		err = m.run(mg.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, mg.Version)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("migrations: rolling back %04d_%s: %w", mg.Version, mg.Name, err)
+		}
+		return mg, nil
+	}
+
+	return nil, ErrNothingToRollBack
+}
+
+// run executes a migration script and the bookkeeping statement inside a
+// single transaction. Note that MySQL commits DDL implicitly, so a failing
+// MySQL script may leave partial changes behind.
+func (m *Migrator) run(script string, record func(tx *sql.Tx) error) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range splitStatements(script) {
+		_, err = tx.Exec(stmt)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = record(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type appliedMigration struct {
+	appliedAt time.Time
+	checksum  string
+}
+
+func (m *Migrator) applied() (map[int]appliedMigration, error) {
+	_, err := m.DB.Exec(createTableStmt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.Query(`SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]appliedMigration{}
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		err = rows.Scan(&version, &a.checksum, &a.appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// verify loads applied migrations and makes sure they all match the
+// embedded files, so that edited or missing migrations are noticed early
+func (m *Migrator) verify() (map[int]appliedMigration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[int]*Migration{}
+	for _, mg := range m.migrations {
+		known[mg.Version] = mg
+	}
+
+	for version, a := range applied {
+		mg, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: version %d", ErrUnknownMigration, version)
+		}
+		if !mg.matches(a.checksum) {
+			return nil, fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, mg.Version, mg.Name)
+		}
+	}
+
+	return applied, nil
+}
+
+// splitStatements splits a script into single statements on semicolons at
+// the end of a line, as not every driver accepts several statements per
+// Exec. Lines between a line ending in BEGIN and the matching "END;" line
+// are kept together so trigger bodies stay intact.
+func splitStatements(script string) []string {
+	var stmts []string
+	var current strings.Builder
+	inBlock := false
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		upper := strings.ToUpper(trimmed)
+		switch {
+		case strings.HasSuffix(upper, "BEGIN"):
+			inBlock = true
+		case inBlock && upper == "END;":
+			inBlock = false
+			stmts = append(stmts, strings.TrimSpace(current.String()))
+			current.Reset()
+		case !inBlock && strings.HasSuffix(trimmed, ";"):
+			stmts = append(stmts, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
@@ -0,0 +1,175 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"snippet.devlake.xyz/internal/assert"
+
+	_ "modernc.org/sqlite"
+)
+
+func newTestMigrator(t *testing.T) *Migrator {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	m, err := New(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestUpAndDown(t *testing.T) {
+	m := newTestMigrator(t)
+	total := len(m.migrations)
+
+	pending, err := m.Pending()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, pending, total)
+
+	count, err := m.Up()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, total)
+
+	// Running up again is a no-op
+	count, err = m.Up()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 0)
+
+	// Down only rolls back the latest migration
+	mg, err := m.Down()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, mg.Version, m.migrations[total-1].Version)
+
+	pending, err = m.Pending()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, pending, 1)
+
+	for i := 1; i < total; i++ {
+		_, err = m.Down()
+		assert.Equal(t, err, nil)
+	}
+
+	_, err = m.Down()
+	assert.Equal(t, errors.Is(err, ErrNothingToRollBack), true)
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	m := newTestMigrator(t)
+
+	_, err := m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate an applied migration whose file was edited afterwards
+	_, err = m.DB.Exec(`UPDATE schema_migrations SET checksum = 'edited' WHERE version = 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.Pending()
+	assert.Equal(t, errors.Is(err, ErrChecksumMismatch), true)
+
+	statuses, err := m.Status()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, statuses[0].ChecksumOK, false)
+	assert.Equal(t, statuses[1].ChecksumOK, true)
+}
+
+func TestSupersededChecksum(t *testing.T) {
+	m := newTestMigrator(t)
+
+	_, err := m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// databases that applied the first version of a fixed migration
+	old := supersededChecksums["sqlite"][7][0]
+	_, err = m.DB.Exec(`UPDATE schema_migrations SET checksum = ? WHERE version = 7`, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := m.Pending()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, pending, 0)
+
+	statuses, err := m.Status()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, statuses[6].ChecksumOK, true)
+}
+
+func TestShortIDBackfill(t *testing.T) {
+	m := newTestMigrator(t)
+	all := m.migrations
+
+	// snippets created before short IDs existed
+	m.migrations = all[:6]
+	_, err := m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		_, err = m.DB.Exec(`INSERT INTO snippets (title, content, created, expires)
+			VALUES ('Old', 'content', datetime('now'), datetime('now', '+1 day'))`)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m.migrations = all
+	_, err = m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var shortID string
+	err = m.DB.QueryRow(`SELECT short_id FROM snippets WHERE id = 42`).Scan(&shortID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, shortID, "s000000042")
+
+	var distinct int
+	err = m.DB.QueryRow(`SELECT COUNT(DISTINCT short_id) FROM snippets WHERE legacy_link`).Scan(&distinct)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, distinct, 1000)
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   int
+	}{
+		{
+			name:   "Single",
+			script: "CREATE TABLE a (id INTEGER);\n",
+			want:   1,
+		},
+		{
+			name:   "Multiple with comments",
+			script: "-- tables\nCREATE TABLE a (\n  id INTEGER\n);\n\nCREATE INDEX idx ON a(id);\n",
+			want:   2,
+		},
+		{
+			name:   "Trigger body",
+			script: "CREATE TRIGGER t AFTER INSERT ON a BEGIN\n  DELETE FROM b;\n  DELETE FROM c;\nEND;\nDROP TABLE d;\n",
+			want:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, len(splitStatements(tt.script)), tt.want)
+		})
+	}
+}
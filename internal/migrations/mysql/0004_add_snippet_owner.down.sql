@@ -0,0 +1,5 @@
+ALTER TABLE snippets DROP FOREIGN KEY fk_snippets_user;
+
+ALTER TABLE snippets DROP INDEX fk_snippets_user;
+
+ALTER TABLE snippets DROP COLUMN user_id;
@@ -0,0 +1,12 @@
+-- base62 IDs are case sensitive, so they need a binary collation
+ALTER TABLE snippets ADD COLUMN short_id CHAR(10) CHARACTER SET ascii COLLATE ascii_bin NULL;
+
+ALTER TABLE snippets ADD COLUMN legacy_link BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- existing snippets get an ID made from their numeric one, which can't collide
+-- like random ones could, and keep working under their numeric link
+UPDATE snippets SET short_id = CONCAT('s', LPAD(id, 9, '0')), legacy_link = TRUE;
+
+ALTER TABLE snippets MODIFY short_id CHAR(10) CHARACTER SET ascii COLLATE ascii_bin NOT NULL;
+
+ALTER TABLE snippets ADD CONSTRAINT snippets_uc_short_id UNIQUE (short_id);
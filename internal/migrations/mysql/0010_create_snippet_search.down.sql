@@ -0,0 +1 @@
+DROP INDEX ft_snippets_search ON snippets;
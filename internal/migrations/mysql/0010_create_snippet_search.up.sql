@@ -0,0 +1 @@
+CREATE FULLTEXT INDEX ft_snippets_search ON snippets(title, content);
@@ -0,0 +1 @@
+DROP INDEX idx_snippets_archive ON snippets;
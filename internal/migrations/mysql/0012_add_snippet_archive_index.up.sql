@@ -0,0 +1 @@
+CREATE INDEX idx_snippets_archive ON snippets(visibility, created, id);
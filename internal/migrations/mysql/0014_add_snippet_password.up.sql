@@ -0,0 +1,2 @@
+-- bcrypt hash of the optional snippet password, NULL when unprotected
+ALTER TABLE snippets ADD COLUMN hashed_password CHAR(60) NULL;
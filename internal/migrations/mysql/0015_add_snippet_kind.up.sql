@@ -0,0 +1,2 @@
+-- "encrypted" snippets hold base64 ciphertext that only browsers with the key can read
+ALTER TABLE snippets ADD COLUMN kind VARCHAR(10) NOT NULL DEFAULT 'text';
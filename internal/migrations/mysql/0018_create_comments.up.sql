@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS comments (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER NOT NULL,
+    parent_id INTEGER NULL,
+    user_id INTEGER NOT NULL,
+    depth INTEGER NOT NULL DEFAULT 0,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    -- deleted comments with replies are kept, without content, to hold the thread together
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    CONSTRAINT fk_comments_snippet
+        FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE,
+    CONSTRAINT fk_comments_parent
+        FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE,
+    CONSTRAINT fk_comments_user
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_comments_snippet ON comments(snippet_id, id);
+
+ALTER TABLE snippets ADD COLUMN comments_disabled BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+-- fails in strict mode while any content is larger than TEXT holds
+ALTER TABLE snippet_files MODIFY content TEXT NOT NULL;
+ALTER TABLE snippet_revisions MODIFY content TEXT NOT NULL;
+ALTER TABLE snippets MODIFY content TEXT NOT NULL;
@@ -0,0 +1,4 @@
+-- TEXT holds at most 64 KB, less than pastes and encrypted snippets may send
+ALTER TABLE snippets MODIFY content MEDIUMTEXT NOT NULL;
+ALTER TABLE snippet_revisions MODIFY content MEDIUMTEXT NOT NULL;
+ALTER TABLE snippet_files MODIFY content MEDIUMTEXT NOT NULL;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS snippet_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    snippet_id INTEGER NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS snippet_revisions_uc_revision ON snippet_revisions(snippet_id, revision);
+
+-- existing snippets start their history with the current content
+INSERT INTO snippet_revisions (snippet_id, revision, title, content, created)
+    SELECT id, 1, title, content, created FROM snippets;
@@ -0,0 +1,3 @@
+ALTER TABLE snippets ADD COLUMN visibility VARCHAR(10) NOT NULL DEFAULT 'public';
+
+CREATE INDEX IF NOT EXISTS idx_snippets_visibility ON snippets(visibility, id);
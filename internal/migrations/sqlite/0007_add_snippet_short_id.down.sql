@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS snippets_uc_short_id;
+
+ALTER TABLE snippets DROP COLUMN legacy_link;
+
+ALTER TABLE snippets DROP COLUMN short_id;
@@ -0,0 +1,9 @@
+ALTER TABLE snippets ADD COLUMN short_id CHAR(10) NULL;
+
+ALTER TABLE snippets ADD COLUMN legacy_link BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- existing snippets get an ID made from their numeric one, which can't collide
+-- like random ones could, and keep working under their numeric link
+UPDATE snippets SET short_id = printf('s%09d', id), legacy_link = TRUE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS snippets_uc_short_id ON snippets(short_id);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_snippets_archive ON snippets(visibility, created, id);
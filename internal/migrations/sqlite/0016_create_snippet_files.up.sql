@@ -0,0 +1,9 @@
+-- files of a snippet besides its main content, in the order they are shown
+CREATE TABLE IF NOT EXISTS snippet_files (
+    snippet_id INTEGER NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    language VARCHAR(20) NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    PRIMARY KEY (snippet_id, position)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_snippets_parent;
+ALTER TABLE snippets DROP COLUMN parent_id;
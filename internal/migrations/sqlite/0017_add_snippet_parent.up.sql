@@ -0,0 +1,3 @@
+-- the snippet a fork was copied from, no foreign key so forks outlive their parent
+ALTER TABLE snippets ADD COLUMN parent_id INTEGER NULL;
+CREATE INDEX IF NOT EXISTS idx_snippets_parent ON snippets(parent_id);
@@ -0,0 +1,2 @@
+ALTER TABLE snippets DROP COLUMN comments_disabled;
+DROP TABLE IF EXISTS comments;
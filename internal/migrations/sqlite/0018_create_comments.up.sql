@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    snippet_id INTEGER NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+    parent_id INTEGER NULL REFERENCES comments(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    depth INTEGER NOT NULL DEFAULT 0,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    -- deleted comments with replies are kept, without content, to hold the thread together
+    deleted BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_snippet ON comments(snippet_id, id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent ON comments(parent_id);
+
+ALTER TABLE snippets ADD COLUMN comments_disabled BOOLEAN NOT NULL DEFAULT FALSE;
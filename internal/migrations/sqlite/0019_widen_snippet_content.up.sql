@@ -0,0 +1 @@
+-- SQLite TEXT columns have no size limit, only the MySQL ones needed widening
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits for the page size of archive listings
+const (
+	DefaultArchiveLimit = 20
+	MaxArchiveLimit     = 100
+)
+
+// Cursor is a position in the snippet archive, given by the creation time
+// and ID of a snippet. Pages are fetched relative to a cursor so browsing
+// stays fast however deep into the archive it goes.
+type Cursor struct {
+	Created time.Time
+	ID      int
+}
+
+// String encodes the cursor as an opaque URL safe token
+func (c Cursor) String() string {
+	s := fmt.Sprintf("%d.%d", c.Created.Unix(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// ParseCursor decodes a token produced by Cursor.String, malformed tokens
+// return ErrInvalidCursor
+func ParseCursor(token string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	created, id, ok := strings.Cut(string(b), ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	sec, err := strconv.ParseInt(created, 10, 64)
+	if err != nil || sec < 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &Cursor{Created: time.Unix(sec, 0).UTC()}
+	c.ID, err = strconv.Atoi(id)
+	if err != nil || c.ID < 1 {
+		return nil, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// ArchivePage is a page of the archive, newest first. Next and Prev point
+// to the older and newer neighbouring pages and are nil when there is none.
+type ArchivePage struct {
+	Snippets []*Snippet
+	Next     *Cursor
+	Prev     *Cursor
+}
+
+// archive returns up to limit unexpired public snippets ordered by creation,
+// newest first. Without a cursor the newest snippets are returned, otherwise
+// the ones older than the cursor, or newer when backward is set. now is the
+// SQL expression for the current time.
+func archive(db *sql.DB, now string, cursor *Cursor, backward bool, limit int) (*ArchivePage, error) {
+	visible := `expires > ` + now + ` AND visibility = 'public'`
+	position := func(c *Cursor) []any {
+		return []any{dbTime(c.Created), c.ID}
+	}
+
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets WHERE ` + visible
+	var args []any
+	switch {
+	case cursor == nil:
+		backward = false
+		stmt += ` ORDER BY created DESC, id DESC`
+	case backward:
+		stmt += ` AND (created, id) > (?, ?) ORDER BY created, id`
+		args = position(cursor)
+	default:
+		stmt += ` AND (created, id) < (?, ?) ORDER BY created DESC, id DESC`
+		args = position(cursor)
+	}
+	stmt += ` LIMIT ?`
+
+	// fetch one extra snippet to know whether there are more in this direction
+	snippets, err := querySnippets(db, stmt, append(args, limit+1)...)
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(snippets) > limit
+	if more {
+		snippets = snippets[:limit]
+	}
+	if backward {
+		slices.Reverse(snippets)
+	}
+
+	page := &ArchivePage{Snippets: snippets}
+	if len(snippets) == 0 {
+		// the cursor itself is the edge of the page in both directions
+		if cursor != nil {
+			page.Prev, err = archiveNeighbour(db, visible, `>`, cursor, position)
+			if err == nil {
+				page.Next, err = archiveNeighbour(db, visible, `<`, cursor, position)
+			}
+		}
+		return page, err
+	}
+
+	first := &Cursor{Created: snippets[0].Created, ID: snippets[0].ID}
+	last := &Cursor{Created: snippets[len(snippets)-1].Created, ID: snippets[len(snippets)-1].ID}
+
+	switch {
+	case backward:
+		if more {
+			page.Prev = first
+		}
+		page.Next, err = archiveNeighbour(db, visible, `<`, last, position)
+	default:
+		if more {
+			page.Next = last
+		}
+		if cursor != nil {
+			page.Prev, err = archiveNeighbour(db, visible, `>`, first, position)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// archiveNeighbour returns c if there are visible snippets on the side of it
+// given by op, "<" for older and ">" for newer ones, and nil otherwise
+func archiveNeighbour(db *sql.DB, visible, op string, c *Cursor, position func(*Cursor) []any) (*Cursor, error) {
+	stmt := `SELECT EXISTS(SELECT 1 FROM snippets WHERE ` + visible + ` AND (created, id) ` + op + ` (?, ?))`
+
+	var exists bool
+	err := db.QueryRow(stmt, position(c)...).Scan(&exists)
+	if err != nil || !exists {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Archive returns a page of unexpired public snippets, newest first,
+// relative to cursor. A nil cursor starts at the newest snippet.
+func (m *SnippetModel) Archive(cursor *Cursor, backward bool, limit int) (*ArchivePage, error) {
+	return archive(m.DB, "UTC_TIMESTAMP()", cursor, backward, limit)
+}
+
+// Archive returns a page of unexpired public snippets, newest first,
+// relative to cursor. A nil cursor starts at the newest snippet.
+func (m *SQLiteSnippetModel) Archive(cursor *Cursor, backward bool, limit int) (*ArchivePage, error) {
+	return archive(m.DB, "datetime('now')", cursor, backward, limit)
+}
@@ -0,0 +1,203 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Limits for comments, enforced by the forms
+const (
+	MaxCommentLength = 2000
+	// MaxCommentDepth is the deepest a reply can be nested, top level
+	// comments have depth 0
+	MaxCommentDepth = 5
+)
+
+// Comment is a comment on a snippet, or a reply to another comment on it
+type Comment struct {
+	Created        time.Time
+	Content        string // "" for deleted comments
+	Author         string
+	SnippetShortID string
+	ID             int
+	SnippetID      int
+	ParentID       int // 0 for top level comments
+	UserID         int
+	Depth          int
+	// Deleted comments are kept, without their content, while they have replies
+	Deleted bool
+}
+
+// CanReply reports whether the comment can be replied to, deleted comments
+// and those nested too deep can't
+func (c *Comment) CanReply() bool {
+	return !c.Deleted && c.Depth < MaxCommentDepth
+}
+
+// CommentModelInterface describes the comment storage operations
+type CommentModelInterface interface {
+	Insert(snippetID, userID, parentID int, content string) (int, error)
+	Get(id int) (*Comment, error)
+	BySnippet(snippetID int) ([]*Comment, error)
+	Delete(id int) error
+}
+
+const commentColumns = `c.id, c.snippet_id, s.short_id, COALESCE(c.parent_id, 0), c.user_id, u.name,
+	c.content, c.created, c.depth, c.deleted`
+
+const commentTables = `comments c JOIN snippets s ON s.id = c.snippet_id JOIN users u ON u.id = c.user_id`
+
+func scanComment(row rowScanner) (*Comment, error) {
+	c := &Comment{}
+	err := row.Scan(&c.ID, &c.SnippetID, &c.SnippetShortID, &c.ParentID, &c.UserID, &c.Author,
+		&c.Content, &c.Created, &c.Depth, &c.Deleted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// insertComment adds a comment to a snippet, replying to parentID unless
+// it's 0. ErrNoRecord is returned when the parent isn't a comment on the
+// same snippet. now is the SQL expression for the current time.
+func insertComment(db *sql.DB, snippetID, userID, parentID int, content, now string) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	depth := 0
+	if parentID != 0 {
+		stmt := `SELECT depth + 1 FROM comments WHERE id = ? AND snippet_id = ?`
+		err = tx.QueryRow(stmt, parentID, snippetID).Scan(&depth)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return 0, ErrNoRecord
+			} else {
+				return 0, err
+			}
+		}
+	}
+
+	stmt := `INSERT INTO comments (snippet_id, parent_id, user_id, depth, content, created)
+		VALUES(?, ?, ?, ?, ?, ` + now + `)`
+
+	result, err := tx.Exec(stmt, snippetID, nullableID(parentID), userID, depth, content)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), tx.Commit()
+}
+
+// getComment returns a single comment
+func getComment(db *sql.DB, id int) (*Comment, error) {
+	stmt := `SELECT ` + commentColumns + ` FROM ` + commentTables + ` WHERE c.id = ?`
+	return scanComment(db.QueryRow(stmt, id))
+}
+
+// queryComments returns all comments on a snippet, oldest first, so that
+// replies always come after the comment they reply to
+func queryComments(db *sql.DB, snippetID int) ([]*Comment, error) {
+	stmt := `SELECT ` + commentColumns + ` FROM ` + commentTables + `
+		WHERE c.snippet_id = ? ORDER BY c.id`
+
+	rows, err := db.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*Comment{}
+
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// deleteComment deletes a comment. A comment with replies only loses its
+// content, so the thread stays readable. Deleted comments left without
+// replies are removed along the way.
+func deleteComment(db *sql.DB, id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE comments SET deleted = TRUE, content = '' WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	err = checkAffected(result)
+	if err != nil {
+		return err
+	}
+
+	for id != 0 {
+		var parentID int
+		var deleted, replied bool
+		stmt := `SELECT COALESCE(parent_id, 0), deleted,
+			EXISTS(SELECT 1 FROM comments r WHERE r.parent_id = c.id) FROM comments c WHERE c.id = ?`
+		err = tx.QueryRow(stmt, id).Scan(&parentID, &deleted, &replied)
+		if err != nil {
+			return err
+		}
+		if !deleted || replied {
+			break
+		}
+
+		_, err = tx.Exec(`DELETE FROM comments WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		id = parentID
+	}
+
+	return tx.Commit()
+}
+
+// CommentModel is the MySQL implementation of CommentModelInterface
+type CommentModel struct {
+	DB *sql.DB
+}
+
+// Insert adds a comment to a snippet, as a reply to parentID unless it's 0,
+// and returns its ID
+func (m *CommentModel) Insert(snippetID, userID, parentID int, content string) (int, error) {
+	return insertComment(m.DB, snippetID, userID, parentID, content, "UTC_TIMESTAMP()")
+}
+
+func (m *CommentModel) Get(id int) (*Comment, error) {
+	return getComment(m.DB, id)
+}
+
+func (m *CommentModel) BySnippet(snippetID int) ([]*Comment, error) {
+	return queryComments(m.DB, snippetID)
+}
+
+// Delete deletes a comment, or only its content while it has replies
+func (m *CommentModel) Delete(id int) error {
+	return deleteComment(m.DB, id)
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// SQLiteCommentModel is the SQLite implementation of CommentModelInterface
+type SQLiteCommentModel struct {
+	DB *sql.DB
+}
+
+// Insert adds a comment to a snippet, as a reply to parentID unless it's 0,
+// and returns its ID
+func (m *SQLiteCommentModel) Insert(snippetID, userID, parentID int, content string) (int, error) {
+	return insertComment(m.DB, snippetID, userID, parentID, content, "datetime('now')")
+}
+
+func (m *SQLiteCommentModel) Get(id int) (*Comment, error) {
+	return getComment(m.DB, id)
+}
+
+func (m *SQLiteCommentModel) BySnippet(snippetID int) ([]*Comment, error) {
+	return queryComments(m.DB, snippetID)
+}
+
+// Delete deletes a comment, or only its content while it has replies
+func (m *SQLiteCommentModel) Delete(id int) error {
+	return deleteComment(m.DB, id)
+}
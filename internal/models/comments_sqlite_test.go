@@ -0,0 +1,127 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"snippet.devlake.xyz/internal/assert"
+)
+
+func TestSQLiteCommentModel(t *testing.T) {
+	db := newTestDB(t)
+	users := SQLiteUserModel{DB: db}
+	snippets := SQLiteSnippetModel{DB: db}
+	m := SQLiteCommentModel{DB: db}
+
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		err := users.Insert("User", email, "pa$$word")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	snippetID, shortID := insertSnippet(t, &snippets, "Commented", 7, 1)
+	otherID, _ := insertSnippet(t, &snippets, "Other", 7, 1)
+
+	first, err := m.Insert(snippetID, 2, 0, "First!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := m.Insert(snippetID, 1, first, "Thanks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := m.Insert(snippetID, 1, 0, "Second")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// replies must be to comments on the same snippet
+	_, err = m.Insert(otherID, 1, first, "Elsewhere")
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	c, err := m.Get(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, c.Content, "Thanks")
+	assert.Equal(t, c.Author, "User")
+	assert.Equal(t, c.SnippetShortID, shortID)
+	assert.Equal(t, c.ParentID, first)
+	assert.Equal(t, c.Depth, 1)
+
+	comments, err := m.BySnippet(snippetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(comments), 3)
+	assert.Equal(t, comments[0].ID, first)
+	assert.Equal(t, comments[1].ID, reply)
+	assert.Equal(t, comments[2].ID, second)
+
+	// a comment with replies only loses its content
+	err = m.Delete(first)
+	assert.Equal(t, err, nil)
+	c, err = m.Get(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, c.Deleted, true)
+	assert.Equal(t, c.Content, "")
+	assert.Equal(t, c.CanReply(), false)
+
+	// deleting the last reply removes the deleted comment too
+	err = m.Delete(reply)
+	assert.Equal(t, err, nil)
+	_, err = m.Get(first)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	comments, err = m.BySnippet(snippetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(comments), 1)
+	assert.Equal(t, comments[0].ID, second)
+
+	err = m.Delete(first)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	// comments go with their snippet
+	err = snippets.Delete(snippetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Get(second)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+}
+
+func TestSQLiteSnippetModelCommentsDisabled(t *testing.T) {
+	db := newTestDB(t)
+	m := SQLiteSnippetModel{DB: db}
+
+	shortID, err := m.Insert(SnippetInput{
+		Title:            "Quiet",
+		Content:          "No comments please",
+		Visibility:       VisibilityPublic,
+		Expires:          daysFromNow(7),
+		CommentsDisabled: true,
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := m.Get(shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, s.CommentsDisabled, true)
+
+	err = m.Update(s.ID, SnippetInput{Title: s.Title, Content: s.Content, Visibility: s.Visibility})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = m.Get(shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, s.CommentsDisabled, false)
+}
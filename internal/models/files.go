@@ -0,0 +1,58 @@
+package models
+
+import "database/sql"
+
+// Limits for the files of a snippet, enforced by the forms
+const (
+	MaxFiles          = 10
+	MaxFileNameLength = 100
+)
+
+// File is a named file of a snippet besides its main content, like the
+// files of a gist
+type File struct {
+	Name     string
+	Language string // "" for plain text
+	Content  string
+}
+
+// setFiles replaces the files of a snippet, keeping their order
+func setFiles(tx *sql.Tx, snippetID int, files []File) error {
+	_, err := tx.Exec(`DELETE FROM snippet_files WHERE snippet_id = ?`, snippetID)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO snippet_files (snippet_id, position, name, language, content) VALUES (?, ?, ?, ?, ?)`
+	for i, f := range files {
+		_, err = tx.Exec(stmt, snippetID, i+1, f.Name, f.Language, f.Content)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachFiles loads the files of a single snippet. Lists of snippets don't
+// show files, so unlike tags they are only loaded for one snippet at a time.
+func attachFiles(db queryer, s *Snippet) error {
+	stmt := `SELECT name, language, content FROM snippet_files WHERE snippet_id = ? ORDER BY position`
+
+	rows, err := db.Query(stmt, s.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	s.Files = []File{}
+	for rows.Next() {
+		var f File
+		err = rows.Scan(&f.Name, &f.Language, &f.Content)
+		if err != nil {
+			return err
+		}
+		s.Files = append(s.Files, f)
+	}
+
+	return rows.Err()
+}
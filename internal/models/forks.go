@@ -0,0 +1,26 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// attachForks loads the parent of a single snippet, if it was forked from
+// one that still exists, and counts the snippet's own forks
+func attachForks(db queryer, s *Snippet) error {
+	now := dbTime(time.Now())
+
+	if s.ParentID != 0 {
+		stmt := `SELECT ` + snippetColumns + ` FROM snippets WHERE id = ? AND expires > ?`
+		parent, err := scanSnippet(db.QueryRow(stmt, s.ParentID, now))
+		switch {
+		case err == nil:
+			s.Parent = parent
+		case !errors.Is(err, ErrNoRecord):
+			return err
+		}
+	}
+
+	stmt := `SELECT COUNT(*) FROM snippets WHERE parent_id = ? AND expires > ?`
+	return db.QueryRow(stmt, s.ID, now).Scan(&s.Forks)
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Revision is a saved version of a snippet. Every insert and update of a
+// snippet stores a new revision, so the latest one matches the snippet.
+type Revision struct {
+	Created   time.Time
+	Title     string
+	Content   string
+	SnippetID int
+	Number    int
+}
+
+const revisionColumns = `snippet_id, revision, title, content, created`
+
+func scanRevision(row rowScanner) (*Revision, error) {
+	r := &Revision{}
+	err := row.Scan(&r.SnippetID, &r.Number, &r.Title, &r.Content, &r.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// queryRevisions returns all revisions of a snippet, newest first
+func queryRevisions(db *sql.DB, snippetID int) ([]*Revision, error) {
+	stmt := `SELECT ` + revisionColumns + ` FROM snippet_revisions
+		WHERE snippet_id = ? ORDER BY revision DESC`
+
+	rows, err := db.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*Revision{}
+
+	for rows.Next() {
+		r, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func getRevision(db *sql.DB, snippetID, number int) (*Revision, error) {
+	stmt := `SELECT ` + revisionColumns + ` FROM snippet_revisions
+		WHERE snippet_id = ? AND revision = ?`
+
+	return scanRevision(db.QueryRow(stmt, snippetID, number))
+}
+
+// Revisions returns all revisions of a snippet, newest first
+func (m *SnippetModel) Revisions(snippetID int) ([]*Revision, error) {
+	return queryRevisions(m.DB, snippetID)
+}
+
+func (m *SnippetModel) Revision(snippetID, number int) (*Revision, error) {
+	return getRevision(m.DB, snippetID, number)
+}
+
+// Revisions returns all revisions of a snippet, newest first
+func (m *SQLiteSnippetModel) Revisions(snippetID int) ([]*Revision, error) {
+	return queryRevisions(m.DB, snippetID)
+}
+
+func (m *SQLiteSnippetModel) Revision(snippetID, number int) (*Revision, error) {
+	return getRevision(m.DB, snippetID, number)
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxSearchTerms limits how many words of a search query are used
+const maxSearchTerms = 10
+
+// SearchTerms splits a search query into lower case words, dropping
+// punctuation and anything else the full-text indexes would treat as syntax
+func SearchTerms(query string) []string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	// drop duplicates, keeping the order
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, word := range words {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// ftsQuery builds a SQLite FTS5 query matching snippets that contain all
+// terms, each as a prefix
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + term + `"*`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// booleanQuery builds a MySQL boolean mode full-text query matching
+// snippets that contain all terms, each as a prefix
+func booleanQuery(terms []string) string {
+	required := make([]string, len(terms))
+	for i, term := range terms {
+		required[i] = "+" + term + "*"
+	}
+	return strings.Join(required, " ")
+}
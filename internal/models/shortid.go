@@ -0,0 +1,84 @@
+package models
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+const (
+	shortIDAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	shortIDLength   = 10
+
+	// inserts are retried with a fresh ID this many times on collisions
+	shortIDAttempts = 3
+)
+
+// newShortID returns a random, URL-safe base62 identifier. IDs always contain
+// a letter so they can't be confused with numeric snippet IDs.
+func newShortID() (string, error) {
+	// largest multiple of len(shortIDAlphabet) that fits in a byte, so
+	// every character is equally likely
+	const limit = 256 - 256%len(shortIDAlphabet)
+
+	id := make([]byte, 0, shortIDLength)
+	buf := make([]byte, shortIDLength*2)
+
+	for len(id) < shortIDLength {
+		_, err := rand.Read(buf)
+		if err != nil {
+			return "", err
+		}
+
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			id = append(id, shortIDAlphabet[int(b)%len(shortIDAlphabet)])
+			if len(id) == shortIDLength {
+				break
+			}
+		}
+
+		// start over if the ID would look like a number
+		if len(id) == shortIDLength && strings.Trim(string(id), "0123456789") == "" {
+			id = id[:0]
+		}
+	}
+
+	return string(id), nil
+}
+
+// ValidShortID reports whether s could be an ID returned by newShortID
+func ValidShortID(s string) bool {
+	if len(s) != shortIDLength {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(shortIDAlphabet, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// insertWithShortID calls insert with a new short ID, retrying with a fresh
+// one as long as isDuplicate reports the ID is already taken
+func insertWithShortID(insert func(shortID string) error, isDuplicate func(error) bool) (string, error) {
+	var err error
+	for attempt := 0; attempt < shortIDAttempts; attempt++ {
+		var shortID string
+		shortID, err = newShortID()
+		if err != nil {
+			return "", err
+		}
+
+		err = insert(shortID)
+		if err == nil {
+			return shortID, nil
+		}
+		if !isDuplicate(err) {
+			return "", err
+		}
+	}
+	return "", err
+}
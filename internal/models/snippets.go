@@ -3,43 +3,169 @@ package models
 import (
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Snippet visibility levels. Unlisted snippets can be viewed by anyone with
+// the link but are never listed, private ones only by their owner.
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
 )
 
+// Snippet kinds. The content of encrypted snippets is base64 encoded
+// ciphertext, encrypted and decrypted in the browser with a key the server
+// never sees.
+const (
+	KindText      = "text"
+	KindEncrypted = "encrypted"
+)
+
+// MaxContentBytes is the most the content columns hold, MEDIUMTEXT on MySQL.
+// Limits on snippet content must stay below it.
+const MaxContentBytes = 1<<24 - 1
+
+// MaxEncryptedBytes limits the size of the ciphertext of encrypted snippets,
+// which is stored base64 encoded
+const MaxEncryptedBytes = 512 * 1024
+
 type Snippet struct {
-	Created time.Time
-	Expires time.Time
-	Title   string
-	Content string
-	ID      int
+	Created    time.Time
+	Expires    time.Time
+	ShortID    string
+	Title      string
+	Content    string
+	Visibility string
+	Language   string // "" for plain text
+	Kind       string
+	Tags       []string
+	Files      []File // only loaded for single snippets
+	ID         int
+	UserID     int
+	// BurnAfterReading snippets are deleted when first read by anyone but their author
+	BurnAfterReading bool
+	// Protected snippets need a password to be viewed by anyone but their author
+	Protected bool
+	// CommentsDisabled snippets don't take new comments
+	CommentsDisabled bool
+	// ParentID is the snippet this one was forked from, 0 if it wasn't
+	ParentID int
+	// Parent is the unexpired snippet this one was forked from and Forks
+	// the number of its unexpired forks, both only loaded for single snippets
+	Parent *Snippet
+	Forks  int
 }
 
-type SnippetModel struct {
-	DB *sql.DB
-}
+// SnippetInput holds the values of a snippet chosen by its author when
+// creating or updating it
+type SnippetInput struct {
+	Title      string
+	Content    string
+	Visibility string
+	Language   string
+	Kind       string // set on insert only, plain text when empty
+	ParentID   int    // set on insert only, the snippet this one is forked from
+	Tags       []string
+	Files      []File
+	Expires    time.Time // Never for no expiry, zero keeps the current expiry time on update
 
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
-	stmt := `INSERT INTO snippets (title, content, created, expires)
-		VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+	BurnAfterReading bool
+	CommentsDisabled bool
+	// Password protects the snippet when not empty, on update an empty
+	// password keeps the current one unless RemovePassword is set
+	Password       string
+	RemovePassword bool
+}
 
-	result, err := m.DB.Exec(stmt, title, content, expires)
-	if err != nil {
-		return 0, err
+// kind returns the snippet kind to insert
+func (input SnippetInput) kind() string {
+	if input.Kind == "" {
+		return KindText
 	}
+	return input.Kind
+}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
+// VisibleTo reports whether the user with the given ID (0 when not
+// logged in) is allowed to view the snippet
+func (s *Snippet) VisibleTo(userID int) bool {
+	if s.Visibility != VisibilityPrivate {
+		return true
 	}
-	return int(id), nil
+	return userID != 0 && s.UserID == userID
+}
+
+// Encrypted reports whether the snippet content is ciphertext
+func (s *Snippet) Encrypted() bool {
+	return s.Kind == KindEncrypted
+}
+
+// Expired reports whether the snippet is past its expiry time
+func (s *Snippet) Expired() bool {
+	return !s.Expires.After(time.Now())
+}
+
+// Never is the expiry time of snippets that don't expire, the largest
+// DATETIME value MySQL supports
+var Never = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
+// NeverExpires reports whether the snippet is kept until deleted
+func (s *Snippet) NeverExpires() bool {
+	return !s.Expires.Before(Never)
+}
+
+// dbTime formats t the way both drivers store DATETIME values, so it can
+// be compared with stored times
+func dbTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// SnippetModelInterface describes the snippet storage operations used by the
+// web application, so that the backing database can be swapped out.
+type SnippetModelInterface interface {
+	Insert(input SnippetInput, userID int) (string, error)
+	Get(shortID string) (*Snippet, error)
+	Read(shortID string, userID int) (*Snippet, error)
+	CheckPassword(id int, password string) error
+	LegacyShortID(id int) (string, error)
+	Latest() ([]*Snippet, error)
+	Archive(cursor *Cursor, backward bool, limit int) (*ArchivePage, error)
+	Search(query string, userID int, limit, offset int) ([]*Snippet, error)
+	ByTag(tag string, userID int, limit, offset int) ([]*Snippet, error)
+	PopularTags(limit int) ([]*TagCount, error)
+	ByUser(userID int) ([]*Snippet, error)
+	Update(id int, input SnippetInput) error
+	SetExpiry(id int, expires time.Time) error
+	Delete(id int) error
+	DeleteExpired(grace time.Duration, limit int) (int, error)
+	Revisions(snippetID int) ([]*Revision, error)
+	Revision(snippetID, number int) (*Revision, error)
+}
+
+// snippetColumns are selected by every snippet query,
+// in the order expected by scanSnippet
+const snippetColumns = `id, short_id, title, content, created, expires, COALESCE(user_id, 0), visibility, language,
+	burn_after_reading, hashed_password IS NOT NULL, kind, COALESCE(parent_id, 0), comments_disabled`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
 }
 
-func (m *SnippetModel) Get(id int) (*Snippet, error) {
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-		WHERE expires > UTC_TIMESTAMP() AND id = ?`
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
 
+func scanSnippet(row rowScanner) (*Snippet, error) {
 	s := &Snippet{}
-	err := m.DB.QueryRow(stmt, id).Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	err := row.Scan(&s.ID, &s.ShortID, &s.Title, &s.Content, &s.Created, &s.Expires, &s.UserID, &s.Visibility, &s.Language,
+		&s.BurnAfterReading, &s.Protected, &s.Kind, &s.ParentID, &s.CommentsDisabled)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNoRecord
@@ -47,15 +173,173 @@ func (m *SnippetModel) Get(id int) (*Snippet, error) {
 			return nil, err
 		}
 	}
+	return s, nil
+}
+
+// getSnippet runs a query selecting snippetColumns of a single snippet and
+// loads its tags, files and forks
+func getSnippet(db *sql.DB, stmt string, args ...any) (*Snippet, error) {
+	s, err := scanSnippet(db.QueryRow(stmt, args...))
+	if err != nil {
+		return nil, err
+	}
+
+	err = attachTags(db, []*Snippet{s})
+	if err != nil {
+		return nil, err
+	}
+
+	err = attachFiles(db, s)
+	if err != nil {
+		return nil, err
+	}
 
+	err = attachForks(db, s)
+	if err != nil {
+		return nil, err
+	}
 	return s, nil
 }
 
-func (m *SnippetModel) Latest() ([]*Snippet, error) {
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-		WHERE expires > UTC_TIMESTAMP() ORDER BY id DESC LIMIT 10`
+// readSnippet runs a query selecting snippetColumns of a single snippet for
+// the user to read. Snippets that don't burn are loaded like Get, without
+// locking. Burn after reading snippets read by anyone but their author are
+// read again with lockStmt and deleted in the same transaction, only the
+// reader whose delete removes the row gets the content.
+func readSnippet(db *sql.DB, stmt, lockStmt string, shortID string, userID int) (*Snippet, error) {
+	s, err := getSnippet(db, stmt, shortID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.VisibleTo(userID) {
+		return nil, ErrNoRecord
+	}
+
+	if !s.BurnAfterReading || (userID != 0 && s.UserID == userID) {
+		return s, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	s, err = scanSnippet(tx.QueryRow(lockStmt, shortID))
+	if err != nil {
+		return nil, err
+	}
+
+	err = attachTags(tx, []*Snippet{s})
+	if err != nil {
+		return nil, err
+	}
+
+	err = attachFiles(tx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	err = attachForks(tx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM snippets WHERE id = ?`, s.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// someone else got to it first
+	err = checkAffected(result)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// setPassword stores the bcrypt hash of a new snippet password, or removes
+// the password, as requested by input
+func setPassword(tx *sql.Tx, snippetID int, input SnippetInput) error {
+	switch {
+	case input.Password != "":
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), 12)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE snippets SET hashed_password = ? WHERE id = ?`, string(hashedPassword), snippetID)
+		return err
+	case input.RemovePassword:
+		_, err := tx.Exec(`UPDATE snippets SET hashed_password = NULL WHERE id = ?`, snippetID)
+		return err
+	}
+	return nil
+}
+
+// checkPassword compares password with the hash stored for the snippet.
+// A wrong password, or a snippet without one, returns ErrInvalidCredentials.
+func checkPassword(db *sql.DB, snippetID int, password string) error {
+	var hashedPassword []byte
+	err := db.QueryRow(`SELECT hashed_password FROM snippets WHERE id = ? AND hashed_password IS NOT NULL`,
+		snippetID).Scan(&hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidCredentials
+		} else {
+			return err
+		}
+	}
+
+	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrInvalidCredentials
+		} else {
+			return err
+		}
+	}
+	return nil
+}
+
+// setExpiry changes the expiry time of a snippet
+func setExpiry(db *sql.DB, snippetID int, expires time.Time) error {
+	result, err := db.Exec(`UPDATE snippets SET expires = ? WHERE id = ?`, dbTime(expires), snippetID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+// nullableID stores an ID of 0 as NULL, so that optional foreign keys stay valid
+func nullableID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+// checkAffected turns an update or delete that matched no rows into ErrNoRecord.
+// On MySQL this needs clientFoundRows, which openDB sets, otherwise updates
+// writing the current values count as matching nothing.
+func checkAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
 
-	rows, err := m.DB.Query(stmt)
+// querySnippets runs a query selecting snippetColumns and collects the
+// results together with their tags
+func querySnippets(db *sql.DB, stmt string, args ...any) ([]*Snippet, error) {
+	rows, err := db.Query(stmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -64,9 +348,7 @@ func (m *SnippetModel) Latest() ([]*Snippet, error) {
 	snippets := []*Snippet{}
 
 	for rows.Next() {
-		// Create a pointer to a new zeroed Snippet struct.
-		s := &Snippet{}
-		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+		s, err := scanSnippet(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -78,6 +360,243 @@ func (m *SnippetModel) Latest() ([]*Snippet, error) {
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
+	rows.Close()
+
+	err = attachTags(db, snippets)
+	if err != nil {
+		return nil, err
+	}
 
 	return snippets, nil
 }
+
+// SnippetModel is the MySQL implementation of SnippetModelInterface
+type SnippetModel struct {
+	DB *sql.DB
+}
+
+// Insert stores a new snippet owned by userID (0 for none) together with
+// its tags, files and first revision and returns the generated short ID
+func (m *SnippetModel) Insert(input SnippetInput, userID int) (string, error) {
+	return insertWithShortID(func(shortID string) error {
+		tx, err := m.DB.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		stmt := `INSERT INTO snippets (short_id, title, content, created, expires, user_id, visibility, language,
+				burn_after_reading, kind, parent_id, comments_disabled)
+			VALUES(?, ?, ?, UTC_TIMESTAMP(), ?, ?, ?, ?, ?, ?, ?, ?)`
+
+		result, err := tx.Exec(stmt, shortID, input.Title, input.Content, dbTime(input.Expires),
+			nullableID(userID), input.Visibility, input.Language, input.BurnAfterReading, input.kind(),
+			nullableID(input.ParentID), input.CommentsDisabled)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		err = setTags(tx, int(id), input.Tags, `INSERT IGNORE INTO tags (name) VALUES (?)`)
+		if err != nil {
+			return err
+		}
+
+		err = setFiles(tx, int(id), input.Files)
+		if err != nil {
+			return err
+		}
+
+		err = setPassword(tx, int(id), input)
+		if err != nil {
+			return err
+		}
+
+		err = m.insertRevision(tx, int(id))
+		if err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	}, m.isDuplicateShortID)
+}
+
+// isDuplicateShortID reports whether err is caused by a taken short ID
+func (m *SnippetModel) isDuplicateShortID(err error) bool {
+	var mySQLError *mysql.MySQLError
+	return errors.As(err, &mySQLError) && mySQLError.Number == 1062 &&
+		strings.Contains(mySQLError.Message, "snippets_uc_short_id")
+}
+
+// insertRevision stores the current title and content of a snippet as its next revision
+func (m *SnippetModel) insertRevision(tx *sql.Tx, snippetID int) error {
+	stmt := `INSERT INTO snippet_revisions (snippet_id, revision, title, content, created)
+		SELECT id, (SELECT COALESCE(MAX(revision), 0) + 1 FROM snippet_revisions WHERE snippet_id = ?),
+			title, content, UTC_TIMESTAMP()
+		FROM snippets WHERE id = ?`
+
+	_, err := tx.Exec(stmt, snippetID, snippetID)
+	return err
+}
+
+func (m *SnippetModel) Get(shortID string) (*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+		WHERE expires > UTC_TIMESTAMP() AND short_id = ?`
+
+	return getSnippet(m.DB, stmt, shortID)
+}
+
+// Read returns the snippet like Get if the user may view it. Burn after
+// reading snippets are deleted when read by anyone but their author.
+func (m *SnippetModel) Read(shortID string, userID int) (*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+		WHERE expires > UTC_TIMESTAMP() AND short_id = ?`
+
+	return readSnippet(m.DB, stmt, stmt+` FOR UPDATE`, shortID, userID)
+}
+
+// CheckPassword returns ErrInvalidCredentials unless password matches the
+// one protecting the snippet
+func (m *SnippetModel) CheckPassword(id int, password string) error {
+	return checkPassword(m.DB, id, password)
+}
+
+// LegacyShortID looks up the short ID of a snippet created before short IDs
+// existed, so that old numeric links can be redirected
+func (m *SnippetModel) LegacyShortID(id int) (string, error) {
+	stmt := `SELECT short_id FROM snippets
+		WHERE expires > UTC_TIMESTAMP() AND legacy_link = TRUE AND id = ?`
+
+	var shortID string
+	err := m.DB.QueryRow(stmt, id).Scan(&shortID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNoRecord
+		} else {
+			return "", err
+		}
+	}
+	return shortID, nil
+}
+
+func (m *SnippetModel) Latest() ([]*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+		WHERE expires > UTC_TIMESTAMP() AND visibility = 'public' ORDER BY id DESC LIMIT 10`
+
+	return querySnippets(m.DB, stmt)
+}
+
+// Search returns the unexpired snippets matching all words of query, most
+// relevant first. Only public snippets without a password and the user's own
+// are included, encrypted snippets never are.
+func (m *SnippetModel) Search(query string, userID int, limit, offset int) ([]*Snippet, error) {
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return []*Snippet{}, nil
+	}
+	q := booleanQuery(terms)
+
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+		WHERE MATCH(title, content) AGAINST(? IN BOOLEAN MODE)
+			AND expires > UTC_TIMESTAMP() AND kind = 'text' AND ((visibility = 'public' AND hashed_password IS NULL) OR user_id = ?)
+		ORDER BY MATCH(title, content) AGAINST(? IN BOOLEAN MODE) DESC, id DESC
+		LIMIT ? OFFSET ?`
+
+	return querySnippets(m.DB, stmt, q, userID, q, limit, offset)
+}
+
+// ByUser returns all snippets owned by the user, including expired ones
+func (m *SnippetModel) ByUser(userID int) ([]*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+		WHERE user_id = ? ORDER BY created DESC, id DESC`
+
+	return querySnippets(m.DB, stmt, userID)
+}
+
+// Update changes the snippet title, content, settings, tags and files
+// and records the title and content as a new revision. A zero input.Expires
+// keeps the current expiry time.
+func (m *SnippetModel) Update(id int, input SnippetInput) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var result sql.Result
+	if !input.Expires.IsZero() {
+		stmt := `UPDATE snippets SET title = ?, content = ?, visibility = ?, language = ?,
+			burn_after_reading = ?, comments_disabled = ?, expires = ? WHERE id = ?`
+		result, err = tx.Exec(stmt, input.Title, input.Content, input.Visibility, input.Language,
+			input.BurnAfterReading, input.CommentsDisabled, dbTime(input.Expires), id)
+	} else {
+		stmt := `UPDATE snippets SET title = ?, content = ?, visibility = ?, language = ?,
+			burn_after_reading = ?, comments_disabled = ? WHERE id = ?`
+		result, err = tx.Exec(stmt, input.Title, input.Content, input.Visibility, input.Language,
+			input.BurnAfterReading, input.CommentsDisabled, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = checkAffected(result)
+	if err != nil {
+		return err
+	}
+
+	err = setTags(tx, id, input.Tags, `INSERT IGNORE INTO tags (name) VALUES (?)`)
+	if err != nil {
+		return err
+	}
+
+	err = setFiles(tx, id, input.Files)
+	if err != nil {
+		return err
+	}
+
+	err = setPassword(tx, id, input)
+	if err != nil {
+		return err
+	}
+
+	err = m.insertRevision(tx, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetExpiry changes when the snippet expires, without recording a revision
+func (m *SnippetModel) SetExpiry(id int, expires time.Time) error {
+	return setExpiry(m.DB, id, expires)
+}
+
+func (m *SnippetModel) Delete(id int) error {
+	result, err := m.DB.Exec(`DELETE FROM snippets WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+// DeleteExpired removes up to limit expired snippets and returns how many were
+// deleted. Snippets with an owner are kept for grace after expiring, so the
+// owner still sees them on the dashboard.
+func (m *SnippetModel) DeleteExpired(grace time.Duration, limit int) (int, error) {
+	stmt := `DELETE FROM snippets WHERE expires <= UTC_TIMESTAMP() AND (user_id IS NULL OR expires <= ?)
+		ORDER BY id LIMIT ?`
+
+	result, err := m.DB.Exec(stmt, dbTime(time.Now().Add(-grace)), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,247 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLiteSnippetModel is the SQLite implementation of SnippetModelInterface
+type SQLiteSnippetModel struct {
+	DB *sql.DB
+}
+
+// Insert stores a new snippet owned by userID (0 for none) together with
+// its tags, files and first revision and returns the generated short ID
+func (m *SQLiteSnippetModel) Insert(input SnippetInput, userID int) (string, error) {
+	return insertWithShortID(func(shortID string) error {
+		tx, err := m.DB.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		stmt := `INSERT INTO snippets (short_id, title, content, created, expires, user_id, visibility, language,
+				burn_after_reading, kind, parent_id, comments_disabled)
+			VALUES(?, ?, ?, datetime('now'), ?, ?, ?, ?, ?, ?, ?, ?)`
+
+		result, err := tx.Exec(stmt, shortID, input.Title, input.Content, dbTime(input.Expires),
+			nullableID(userID), input.Visibility, input.Language, input.BurnAfterReading, input.kind(),
+			nullableID(input.ParentID), input.CommentsDisabled)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		err = setTags(tx, int(id), input.Tags, `INSERT OR IGNORE INTO tags (name) VALUES (?)`)
+		if err != nil {
+			return err
+		}
+
+		err = setFiles(tx, int(id), input.Files)
+		if err != nil {
+			return err
+		}
+
+		err = setPassword(tx, int(id), input)
+		if err != nil {
+			return err
+		}
+
+		err = m.insertRevision(tx, int(id))
+		if err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	}, m.isDuplicateShortID)
+}
+
+// isDuplicateShortID reports whether err is caused by a taken short ID
+func (m *SQLiteSnippetModel) isDuplicateShortID(err error) bool {
+	var sqliteError *sqlite.Error
+	return errors.As(err, &sqliteError) && sqliteError.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE &&
+		strings.Contains(sqliteError.Error(), "snippets.short_id")
+}
+
+// insertRevision stores the current title and content of a snippet as its next revision
+func (m *SQLiteSnippetModel) insertRevision(tx *sql.Tx, snippetID int) error {
+	stmt := `INSERT INTO snippet_revisions (snippet_id, revision, title, content, created)
+		SELECT id, (SELECT COALESCE(MAX(revision), 0) + 1 FROM snippet_revisions WHERE snippet_id = ?),
+			title, content, datetime('now')
+		FROM snippets WHERE id = ?`
+
+	_, err := tx.Exec(stmt, snippetID, snippetID)
+	return err
+}
+
+func (m *SQLiteSnippetModel) Get(shortID string) (*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+		WHERE expires > datetime('now') AND short_id = ?`
+
+	return getSnippet(m.DB, stmt, shortID)
+}
+
+// Read returns the snippet like Get if the user may view it. Burn after
+// reading snippets are deleted when read by anyone but their author. Open
+// the database with _txlock=immediate, so that concurrent readers of a burn
+// after reading snippet wait for each other instead of failing with a busy
+// error.
+func (m *SQLiteSnippetModel) Read(shortID string, userID int) (*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+		WHERE expires > datetime('now') AND short_id = ?`
+
+	return readSnippet(m.DB, stmt, stmt, shortID, userID)
+}
+
+// CheckPassword returns ErrInvalidCredentials unless password matches the
+// one protecting the snippet
+func (m *SQLiteSnippetModel) CheckPassword(id int, password string) error {
+	return checkPassword(m.DB, id, password)
+}
+
+// LegacyShortID looks up the short ID of a snippet created before short IDs
+// existed, so that old numeric links can be redirected
+func (m *SQLiteSnippetModel) LegacyShortID(id int) (string, error) {
+	stmt := `SELECT short_id FROM snippets
+		WHERE expires > datetime('now') AND legacy_link = TRUE AND id = ?`
+
+	var shortID string
+	err := m.DB.QueryRow(stmt, id).Scan(&shortID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNoRecord
+		} else {
+			return "", err
+		}
+	}
+	return shortID, nil
+}
+
+func (m *SQLiteSnippetModel) Latest() ([]*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+		WHERE expires > datetime('now') AND visibility = 'public' ORDER BY id DESC LIMIT 10`
+
+	return querySnippets(m.DB, stmt)
+}
+
+// Search returns the unexpired snippets matching all words of query, most
+// relevant first. Only public snippets without a password and the user's own
+// are included, encrypted snippets never are.
+func (m *SQLiteSnippetModel) Search(query string, userID int, limit, offset int) ([]*Snippet, error) {
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return []*Snippet{}, nil
+	}
+
+	// bm25 ranks lower values as better matches, title matches count more
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+		JOIN (SELECT rowid, bm25(snippets_fts, 5.0, 1.0) AS rank
+			FROM snippets_fts WHERE snippets_fts MATCH ?) AS matches ON matches.rowid = snippets.id
+		WHERE expires > datetime('now') AND kind = 'text' AND ((visibility = 'public' AND hashed_password IS NULL) OR user_id = ?)
+		ORDER BY matches.rank, id DESC
+		LIMIT ? OFFSET ?`
+
+	return querySnippets(m.DB, stmt, ftsQuery(terms), userID, limit, offset)
+}
+
+// ByUser returns all snippets owned by the user, including expired ones
+func (m *SQLiteSnippetModel) ByUser(userID int) ([]*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+		WHERE user_id = ? ORDER BY created DESC, id DESC`
+
+	return querySnippets(m.DB, stmt, userID)
+}
+
+// Update changes the snippet title, content, settings, tags and files
+// and records the title and content as a new revision. A zero input.Expires
+// keeps the current expiry time.
+func (m *SQLiteSnippetModel) Update(id int, input SnippetInput) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var result sql.Result
+	if !input.Expires.IsZero() {
+		stmt := `UPDATE snippets SET title = ?, content = ?, visibility = ?, language = ?,
+			burn_after_reading = ?, comments_disabled = ?, expires = ? WHERE id = ?`
+		result, err = tx.Exec(stmt, input.Title, input.Content, input.Visibility, input.Language,
+			input.BurnAfterReading, input.CommentsDisabled, dbTime(input.Expires), id)
+	} else {
+		stmt := `UPDATE snippets SET title = ?, content = ?, visibility = ?, language = ?,
+			burn_after_reading = ?, comments_disabled = ? WHERE id = ?`
+		result, err = tx.Exec(stmt, input.Title, input.Content, input.Visibility, input.Language,
+			input.BurnAfterReading, input.CommentsDisabled, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = checkAffected(result)
+	if err != nil {
+		return err
+	}
+
+	err = setTags(tx, id, input.Tags, `INSERT OR IGNORE INTO tags (name) VALUES (?)`)
+	if err != nil {
+		return err
+	}
+
+	err = setFiles(tx, id, input.Files)
+	if err != nil {
+		return err
+	}
+
+	err = setPassword(tx, id, input)
+	if err != nil {
+		return err
+	}
+
+	err = m.insertRevision(tx, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetExpiry changes when the snippet expires, without recording a revision
+func (m *SQLiteSnippetModel) SetExpiry(id int, expires time.Time) error {
+	return setExpiry(m.DB, id, expires)
+}
+
+func (m *SQLiteSnippetModel) Delete(id int) error {
+	result, err := m.DB.Exec(`DELETE FROM snippets WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+// DeleteExpired removes up to limit expired snippets and returns how many were
+// deleted. Snippets with an owner are kept for grace after expiring, so the
+// owner still sees them on the dashboard.
+func (m *SQLiteSnippetModel) DeleteExpired(grace time.Duration, limit int) (int, error) {
+	stmt := `DELETE FROM snippets WHERE id IN (
+		SELECT id FROM snippets WHERE expires <= datetime('now') AND (user_id IS NULL OR expires <= ?)
+		ORDER BY id LIMIT ?)`
+
+	result, err := m.DB.Exec(stmt, dbTime(time.Now().Add(-grace)), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	return int(n), err
+}
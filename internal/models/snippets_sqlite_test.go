@@ -0,0 +1,824 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"snippet.devlake.xyz/internal/assert"
+)
+
+func TestSQLiteSnippetModel(t *testing.T) {
+	m := SQLiteSnippetModel{DB: newTestDB(t)}
+
+	expires := daysFromNow(7)
+	shortID, err := m.Insert(SnippetInput{Title: "An old silent pond", Content: "An old silent pond...", Visibility: VisibilityPublic, Expires: expires}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ValidShortID(shortID), true)
+
+	s, err := m.Get(shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, s.ShortID, shortID)
+	assert.Equal(t, s.Title, "An old silent pond")
+	assert.Equal(t, s.Expires.Equal(expires), true)
+
+	// Unknown IDs are reported as missing records
+	_, err = m.Get("0000000000")
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	// Snippets created with a short ID have no legacy numeric link
+	_, err = m.LegacyShortID(s.ID)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	latest, err := m.Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(latest), 1)
+	assert.Equal(t, latest[0].ShortID, shortID)
+}
+
+func TestSQLiteSnippetModelByUser(t *testing.T) {
+	db := newTestDB(t)
+	m := SQLiteSnippetModel{DB: db}
+	users := SQLiteUserModel{DB: db}
+
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		err := users.Insert("Test User", email, "pa$$word")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	aliceSnippet, _ := insertSnippet(t, &m, "Alice's snippet", 7, 1)
+	insertSnippet(t, &m, "Bob's snippet", 7, 2)
+
+	expired, _ := insertSnippet(t, &m, "Alice's old snippet", 7, 1)
+	_, err := db.Exec(`UPDATE snippets SET expires = datetime('now', '-1 day') WHERE id = ?`, expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Expired snippets are still listed for their owner
+	snippets, err := m.ByUser(1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(snippets), 2)
+	assert.Equal(t, snippets[0].ID, expired)
+	assert.Equal(t, snippets[0].Expired(), true)
+	assert.Equal(t, snippets[1].ID, aliceSnippet)
+	assert.Equal(t, snippets[1].UserID, 1)
+}
+
+func TestSQLiteSnippetModelUpdateDelete(t *testing.T) {
+	m := SQLiteSnippetModel{DB: newTestDB(t)}
+
+	id, shortID := insertSnippet(t, &m, "Title", 1, 0)
+	before, err := m.Get(shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a zero Expires keeps the current expiry time
+	err = m.Update(id, SnippetInput{Title: "New title", Content: "new content", Visibility: VisibilityPublic, Language: "go"})
+	assert.Equal(t, err, nil)
+	s, err := m.Get(shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, s.Title, "New title")
+	assert.Equal(t, s.Content, "new content")
+	assert.Equal(t, s.Language, "go")
+	assert.Equal(t, s.Expires.Equal(before.Expires), true)
+
+	err = m.Update(id, SnippetInput{Title: "New title", Content: "new content", Visibility: VisibilityPublic, Expires: daysFromNow(7)})
+	assert.Equal(t, err, nil)
+	s, err = m.Get(shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, s.Expires.After(before.Expires), true)
+
+	err = m.Delete(id)
+	assert.Equal(t, err, nil)
+	_, err = m.Get(shortID)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	// Deleting or updating a missing snippet reports ErrNoRecord
+	assert.Equal(t, errors.Is(m.Delete(id), ErrNoRecord), true)
+	assert.Equal(t, errors.Is(m.Update(id, SnippetInput{Title: "t", Content: "c", Visibility: VisibilityPublic}), ErrNoRecord), true)
+}
+
+func TestSQLiteSnippetModelSetExpiry(t *testing.T) {
+	m := SQLiteSnippetModel{DB: newTestDB(t)}
+
+	id, shortID := insertSnippet(t, &m, "Title", 1, 0)
+
+	expires := daysFromNow(30).Add(90 * time.Minute)
+	err := m.SetExpiry(id, expires)
+	assert.Equal(t, err, nil)
+	s, err := m.Get(shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, s.Expires.Equal(expires), true)
+	assert.Equal(t, s.NeverExpires(), false)
+
+	// setting the current expiry again isn't mistaken for a missing snippet
+	err = m.SetExpiry(id, expires)
+	assert.Equal(t, err, nil)
+
+	err = m.SetExpiry(id, Never)
+	assert.Equal(t, err, nil)
+	s, err = m.Get(shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, s.NeverExpires(), true)
+
+	// changing the expiry doesn't record a new revision
+	revisions, err := m.Revisions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(revisions), 1)
+
+	assert.Equal(t, errors.Is(m.SetExpiry(id+1, Never), ErrNoRecord), true)
+}
+
+func TestSQLiteSnippetModelRevisions(t *testing.T) {
+	m := SQLiteSnippetModel{DB: newTestDB(t)}
+
+	id, _ := insertSnippet(t, &m, "First", 7, 0)
+	err := m.Update(id, SnippetInput{Title: "Second", Content: "two", Visibility: VisibilityPublic})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every saved version is kept, newest first
+	revisions, err := m.Revisions(id)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(revisions), 2)
+	assert.Equal(t, revisions[0].Number, 2)
+	assert.Equal(t, revisions[0].Content, "two")
+	assert.Equal(t, revisions[1].Number, 1)
+	assert.Equal(t, revisions[1].Title, "First")
+	assert.Equal(t, revisions[1].Content, "content of First")
+
+	r, err := m.Revision(id, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, r.Content, "content of First")
+
+	_, err = m.Revision(id, 3)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	// Revisions are removed together with the snippet
+	err = m.Delete(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err = m.Revisions(id)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(revisions), 0)
+}
+
+func TestSnippetVisibleTo(t *testing.T) {
+	tests := []struct {
+		name       string
+		visibility string
+		userID     int
+		want       bool
+	}{
+		{name: "Public anonymous", visibility: VisibilityPublic, userID: 0, want: true},
+		{name: "Unlisted anonymous", visibility: VisibilityUnlisted, userID: 0, want: true},
+		{name: "Private anonymous", visibility: VisibilityPrivate, userID: 0, want: false},
+		{name: "Private other user", visibility: VisibilityPrivate, userID: 2, want: false},
+		{name: "Private owner", visibility: VisibilityPrivate, userID: 1, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Snippet{Visibility: tt.visibility, UserID: 1}
+			assert.Equal(t, s.VisibleTo(tt.userID), tt.want)
+		})
+	}
+}
+
+func TestSQLiteSnippetModelLatestVisibility(t *testing.T) {
+	m := SQLiteSnippetModel{DB: newTestDB(t)}
+
+	for _, v := range []string{VisibilityPublic, VisibilityUnlisted, VisibilityPrivate} {
+		_, err := m.Insert(SnippetInput{Title: "Title", Content: "content", Visibility: v, Expires: daysFromNow(7)}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only public snippets are listed
+	snippets, err := m.Latest()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(snippets), 1)
+	assert.Equal(t, snippets[0].Visibility, VisibilityPublic)
+}
+
+func TestSQLiteSnippetModelLegacyShortID(t *testing.T) {
+	m := SQLiteSnippetModel{DB: newTestDB(t)}
+
+	id, shortID := insertSnippet(t, &m, "Old snippet", 7, 0)
+
+	// Mark the snippet as created before short IDs existed
+	_, err := m.DB.Exec(`UPDATE snippets SET legacy_link = TRUE WHERE id = ?`, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy, err := m.LegacyShortID(id)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, legacy, shortID)
+}
+
+func TestNewShortID(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		id, err := newShortID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, ValidShortID(id), true)
+		assert.Equal(t, seen[id], false)
+		seen[id] = true
+	}
+
+	assert.Equal(t, ValidShortID("abc"), false)
+	assert.Equal(t, ValidShortID("abcdefghi-"), false)
+}
+
+func TestSQLiteSnippetModelDeleteExpired(t *testing.T) {
+	db := newTestDB(t)
+	m := SQLiteSnippetModel{DB: db}
+	users := SQLiteUserModel{DB: db}
+
+	err := users.Insert("Test User", "alice@example.com", "pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expire := func(id int, days int) {
+		_, err := db.Exec(`UPDATE snippets SET expires = datetime('now', ?) WHERE id = ?`,
+			fmt.Sprintf("-%d days", days), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		id, _ := insertSnippet(t, &m, "Expired", 7, 0)
+		expire(id, 1)
+	}
+	_, activeID := insertSnippet(t, &m, "Active", 7, 0)
+
+	// expired snippets of users are kept for the grace period
+	recentID, _ := insertSnippet(t, &m, "Recently expired", 7, 1)
+	expire(recentID, 1)
+	oldID, _ := insertSnippet(t, &m, "Long expired", 7, 1)
+	expire(oldID, 31)
+
+	grace := 30 * 24 * time.Hour
+
+	// Expired snippets are removed in batches
+	n, err := m.DeleteExpired(grace, 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, n, 2)
+
+	n, err = m.DeleteExpired(grace, 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, n, 2)
+
+	n, err = m.DeleteExpired(grace, 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, n, 0)
+
+	_, err = m.Get(activeID)
+	assert.Equal(t, err, nil)
+
+	// the dashboard still lists the recently expired one
+	snippets, err := m.ByUser(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(snippets), 1)
+	assert.Equal(t, snippets[0].ID, recentID)
+	assert.Equal(t, snippets[0].Expired(), true)
+
+	n, err = m.DeleteExpired(0, 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, n, 1)
+}
+
+func TestSQLiteSnippetModelSearch(t *testing.T) {
+	db := newTestDB(t)
+	m := SQLiteSnippetModel{DB: db}
+	users := SQLiteUserModel{DB: db}
+
+	err := users.Insert("Alice", "alice@example.com", "pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	insert := func(title, content, visibility string, userID int) string {
+		shortID, err := m.Insert(SnippetInput{Title: title, Content: content, Visibility: visibility, Expires: daysFromNow(7)}, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return shortID
+	}
+
+	inContent := insert("Notes", "deploying the frog service", VisibilityPublic, 0)
+	inTitle := insert("Frog deployment", "steps to follow", VisibilityPublic, 0)
+	insert("Frog unlisted", "hidden", VisibilityUnlisted, 0)
+	private := insert("Frog private", "mine", VisibilityPrivate, 1)
+	expired := insert("Frog expired", "old", VisibilityPublic, 0)
+	_, err = db.Exec(`UPDATE snippets SET expires = datetime('now', '-1 day') WHERE short_id = ?`, expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func(snippets []*Snippet) string {
+		var s []string
+		for _, snippet := range snippets {
+			s = append(s, snippet.ShortID)
+		}
+		return strings.Join(s, ",")
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		userID int
+		want   string
+	}{
+		{name: "Title ranks first", query: "frog", want: inTitle + "," + inContent},
+		{name: "Owner sees private", query: "frog", userID: 1, want: private + "," + inTitle + "," + inContent},
+		{name: "Prefix", query: "deploy", want: inTitle + "," + inContent},
+		{name: "All terms", query: "frog service", want: inContent},
+		{name: "Case and punctuation", query: `FROG "service"*)`, want: inContent},
+		{name: "No match", query: "toad"},
+		{name: "Empty", query: " ?! "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippets, err := m.Search(tt.query, tt.userID, 10, 0)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, ids(snippets), tt.want)
+		})
+	}
+
+	// Pages are taken from the ranked results
+	snippets, err := m.Search("frog", 0, 1, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ids(snippets), inContent)
+
+	// The index follows updates and deletes
+	id, _ := insertSnippet(t, &m, "Toad", 7, 0)
+	err = m.Update(id, SnippetInput{Title: "Toad", Content: "now about a frog", Visibility: VisibilityPublic})
+	assert.Equal(t, err, nil)
+	snippets, err = m.Search("toad frog", 0, 10, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(snippets), 1)
+
+	err = m.Delete(id)
+	assert.Equal(t, err, nil)
+	snippets, err = m.Search("toad", 0, 10, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(snippets), 0)
+}
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, strings.Join(SearchTerms(`Hello, "world" hello-AND* Zürich`), " "), "hello world and zürich")
+	assert.Equal(t, len(SearchTerms(strings.Repeat("a b c d e f g h i j k l ", 2))), maxSearchTerms)
+}
+
+func TestSQLiteSnippetModelTags(t *testing.T) {
+	db := newTestDB(t)
+	m := SQLiteSnippetModel{DB: db}
+
+	insert := func(title, visibility string, tags ...string) (int, string) {
+		shortID, err := m.Insert(SnippetInput{Title: title, Content: "content", Visibility: visibility, Tags: tags, Expires: daysFromNow(7)}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var id int
+		err = db.QueryRow(`SELECT id FROM snippets WHERE short_id = ?`, shortID).Scan(&id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id, shortID
+	}
+
+	id, shortID := insert("First", VisibilityPublic, "go", "sql")
+	insert("Second", VisibilityPublic, "go")
+	insert("Unlisted", VisibilityUnlisted, "go", "secret")
+	insert("Untagged", VisibilityPublic)
+
+	// Tags are loaded sorted by name
+	s, err := m.Get(shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, strings.Join(s.Tags, ","), "go,sql")
+
+	latest, err := m.Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(latest), 3)
+	assert.Equal(t, len(latest[0].Tags), 0)
+	assert.Equal(t, strings.Join(latest[1].Tags, ","), "go")
+
+	// Only public snippets are listed by tag and counted
+	byTag, err := m.ByTag("go", 0, 10, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(byTag), 2)
+
+	popular, err := m.PopularTags(10)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(popular), 2)
+	assert.Equal(t, *popular[0], TagCount{Name: "go", Count: 2})
+	assert.Equal(t, *popular[1], TagCount{Name: "sql", Count: 1})
+
+	// Updating replaces the tags
+	err = m.Update(id, SnippetInput{Title: "First", Content: "content", Visibility: VisibilityPublic, Tags: []string{"rust"}})
+	assert.Equal(t, err, nil)
+	s, err = m.Get(shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, strings.Join(s.Tags, ","), "rust")
+
+	byTag, err = m.ByTag("sql", 0, 10, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(byTag), 0)
+}
+
+func TestSQLiteSnippetModelArchive(t *testing.T) {
+	db := newTestDB(t)
+	m := SQLiteSnippetModel{DB: db}
+
+	// seven public snippets, the last two created in the same second
+	var ids []int
+	for i := 1; i <= 7; i++ {
+		id, _ := insertSnippet(t, &m, fmt.Sprintf("Snippet %d", i), 7, 0)
+		created := time.Date(2024, 1, min(i, 6), 12, 0, 0, 0, time.UTC).Format("2006-01-02 15:04:05")
+		_, err := db.Exec(`UPDATE snippets SET created = ? WHERE id = ?`, created, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	_, err := m.Insert(SnippetInput{Title: "Hidden", Content: "content", Visibility: VisibilityUnlisted, Expires: daysFromNow(7)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	titles := func(page *ArchivePage) string {
+		var s []string
+		for _, snippet := range page.Snippets {
+			s = append(s, snippet.Title[len("Snippet "):])
+		}
+		return strings.Join(s, ",")
+	}
+
+	first, err := m.Archive(nil, false, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, titles(first), "7,6,5")
+	assert.Equal(t, first.Prev == nil, true)
+
+	second, err := m.Archive(first.Next, false, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, titles(second), "4,3,2")
+	assert.Equal(t, second.Prev != nil && second.Next != nil, true)
+
+	third, err := m.Archive(second.Next, false, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, titles(third), "1")
+	assert.Equal(t, third.Next == nil, true)
+
+	// going back returns the same pages
+	back, err := m.Archive(third.Prev, true, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, titles(back), "4,3,2")
+
+	back, err = m.Archive(back.Prev, true, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, titles(back), "7,6,5")
+	assert.Equal(t, back.Prev == nil, true)
+	assert.Equal(t, back.Next != nil, true)
+
+	// cursors survive a round trip through their string form
+	c, err := ParseCursor(first.Next.String())
+	assert.Equal(t, err, nil)
+	assert.Equal(t, c.Created.Equal(first.Next.Created), true)
+	assert.Equal(t, c.ID, first.Next.ID)
+
+	for _, token := range []string{"", "garbage!", "MTIz", "LTEuMQ"} {
+		_, err = ParseCursor(token)
+		assert.Equal(t, err, ErrInvalidCursor)
+	}
+}
+
+func TestSQLiteSnippetModelRead(t *testing.T) {
+	db := newTestDB(t)
+	m := SQLiteSnippetModel{DB: db}
+	users := SQLiteUserModel{DB: db}
+
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		err := users.Insert("Test User", email, "pa$$word")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	insert := func(visibility string, burn bool) string {
+		shortID, err := m.Insert(SnippetInput{
+			Title:            "Secret",
+			Content:          "content",
+			Visibility:       visibility,
+			Tags:             []string{"secret"},
+			Expires:          daysFromNow(7),
+			BurnAfterReading: burn,
+		}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return shortID
+	}
+
+	// Ordinary snippets can be read any number of times
+	shortID := insert(VisibilityUnlisted, false)
+	for i := 0; i < 2; i++ {
+		s, err := m.Read(shortID, 2)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, s.Content, "content")
+	}
+
+	// The author can read a burn after reading snippet without burning it
+	shortID = insert(VisibilityUnlisted, true)
+	s, err := m.Read(shortID, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, s.BurnAfterReading, true)
+
+	// The first other reader gets it, including its tags, and it is gone afterwards
+	s, err = m.Read(shortID, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, s.Content, "content")
+	assert.Equal(t, strings.Join(s.Tags, ","), "secret")
+
+	_, err = m.Read(shortID, 2)
+	assert.Equal(t, err, ErrNoRecord)
+	_, err = m.Get(shortID)
+	assert.Equal(t, err, ErrNoRecord)
+
+	// Private snippets aren't burned by users who can't see them
+	shortID = insert(VisibilityPrivate, true)
+	_, err = m.Read(shortID, 2)
+	assert.Equal(t, err, ErrNoRecord)
+	_, err = m.Get(shortID)
+	assert.Equal(t, err, nil)
+}
+
+func TestSQLiteSnippetModelReadConcurrent(t *testing.T) {
+	m := SQLiteSnippetModel{DB: newTestDB(t)}
+
+	shortID, err := m.Insert(SnippetInput{
+		Title:            "Secret",
+		Content:          "content",
+		Visibility:       VisibilityUnlisted,
+		Expires:          daysFromNow(7),
+		BurnAfterReading: true,
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two people opening the link at once
+	const readers = 2
+	errs := make(chan error, readers)
+	var start sync.WaitGroup
+	start.Add(1)
+	for i := 0; i < readers; i++ {
+		go func() {
+			start.Wait()
+			_, err := m.Read(shortID, 0)
+			errs <- err
+		}()
+	}
+	start.Done()
+
+	got := 0
+	for i := 0; i < readers; i++ {
+		err := <-errs
+		if err == nil {
+			got++
+		} else if !errors.Is(err, ErrNoRecord) {
+			t.Fatal(err)
+		}
+	}
+	assert.Equal(t, got, 1)
+}
+
+func TestSQLiteSnippetModelPassword(t *testing.T) {
+	db := newTestDB(t)
+	m := SQLiteSnippetModel{DB: db}
+
+	shortID, err := m.Insert(SnippetInput{Title: "Locked", Content: "hidden words", Visibility: VisibilityPublic, Expires: daysFromNow(7), Password: "open sesame"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := m.Get(shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, s.Protected, true)
+
+	assert.Equal(t, m.CheckPassword(s.ID, "open sesame"), nil)
+	assert.Equal(t, m.CheckPassword(s.ID, "wrong"), ErrInvalidCredentials)
+
+	// Protected content isn't searchable by others
+	found, err := m.Search("hidden", 0, 10, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(found), 0)
+
+	// An empty password keeps the current one
+	err = m.Update(s.ID, SnippetInput{Title: "Locked", Content: "hidden words", Visibility: VisibilityPublic})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, m.CheckPassword(s.ID, "open sesame"), nil)
+
+	err = m.Update(s.ID, SnippetInput{Title: "Locked", Content: "hidden words", Visibility: VisibilityPublic, Password: "new one"})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, m.CheckPassword(s.ID, "open sesame"), ErrInvalidCredentials)
+	assert.Equal(t, m.CheckPassword(s.ID, "new one"), nil)
+
+	err = m.Update(s.ID, SnippetInput{Title: "Locked", Content: "hidden words", Visibility: VisibilityPublic, RemovePassword: true})
+	assert.Equal(t, err, nil)
+	s, err = m.Get(shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, s.Protected, false)
+	assert.Equal(t, m.CheckPassword(s.ID, "new one"), ErrInvalidCredentials)
+}
+
+func TestSQLiteSnippetModelEncrypted(t *testing.T) {
+	db := newTestDB(t)
+	m := SQLiteSnippetModel{DB: db}
+
+	_, plain := insertSnippet(t, &m, "Plain", 7, 0)
+	shortID, err := m.Insert(SnippetInput{Title: "Encrypted", Content: "c2VjcmV0", Visibility: VisibilityPublic, Kind: KindEncrypted, Expires: daysFromNow(7)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := m.Get(plain)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, s.Kind, KindText)
+
+	s, err = m.Get(shortID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, s.Encrypted(), true)
+
+	// the ciphertext isn't searchable, not even by its title
+	found, err := m.Search("encrypted", 0, 10, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(found), 0)
+}
+
+func TestSQLiteSnippetModelFiles(t *testing.T) {
+	db := newTestDB(t)
+	m := SQLiteSnippetModel{DB: db}
+
+	files := []File{
+		{Name: "main.go", Language: "go", Content: "package main"},
+		{Name: "README.md", Language: "markdown", Content: "# Hello"},
+	}
+	shortID, err := m.Insert(SnippetInput{Title: "Gist", Content: "notes", Visibility: VisibilityPublic, Files: files, Expires: daysFromNow(7)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// files keep their order
+	s, err := m.Get(shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(s.Files), 2)
+	assert.Equal(t, s.Files[0], files[0])
+	assert.Equal(t, s.Files[1], files[1])
+
+	// updating replaces all files
+	err = m.Update(s.ID, SnippetInput{Title: "Gist", Content: "notes", Visibility: VisibilityPublic, Files: files[1:]})
+	assert.Equal(t, err, nil)
+	s, err = m.Get(shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(s.Files), 1)
+	assert.Equal(t, s.Files[0].Name, "README.md")
+
+	// snippets without files have none
+	_, plain := insertSnippet(t, &m, "Plain", 7, 0)
+	s, err = m.Read(plain, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(s.Files), 0)
+
+	// files are removed together with the snippet
+	s, err = m.Get(shortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Delete(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM snippet_files`).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, count, 0)
+}
+
+func TestSQLiteSnippetModelForks(t *testing.T) {
+	db := newTestDB(t)
+	m := SQLiteSnippetModel{DB: db}
+
+	parentID, parent := insertSnippet(t, &m, "Original", 7, 0)
+	for i := 0; i < 2; i++ {
+		_, err := m.Insert(SnippetInput{Title: "Copy", Content: "content", Visibility: VisibilityPublic, ParentID: parentID, Expires: daysFromNow(7)}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := m.Get(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, s.ParentID, 0)
+	assert.Equal(t, s.Parent == nil, true)
+	assert.Equal(t, s.Forks, 2)
+
+	// forks link back to their parent
+	forks, err := m.Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fork, err := m.Get(forks[0].ShortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fork.ParentID, parentID)
+	assert.Equal(t, fork.Parent.ShortID, parent)
+	assert.Equal(t, fork.Forks, 0)
+
+	// expired forks aren't counted
+	_, err = db.Exec(`UPDATE snippets SET expires = datetime('now', '-1 day') WHERE short_id = ?`, fork.ShortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = m.Get(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, s.Forks, 1)
+
+	// forks outlive their parent
+	err = m.Delete(parentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fork, err = m.Get(forks[1].ShortID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fork.ParentID, parentID)
+	assert.Equal(t, fork.Parent == nil, true)
+}
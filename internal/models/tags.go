@@ -0,0 +1,149 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// Limits for snippet tags, enforced by the forms
+const (
+	MaxTags      = 5
+	MaxTagLength = 30
+)
+
+// TagCount is a tag together with the number of snippets using it
+type TagCount struct {
+	Name  string
+	Count int
+}
+
+// setTags replaces the tags of a snippet. insertTag is the driver specific
+// statement adding a tag name to the tags table unless it already exists.
+func setTags(tx *sql.Tx, snippetID int, tags []string, insertTag string) error {
+	_, err := tx.Exec(`DELETE FROM snippet_tags WHERE snippet_id = ?`, snippetID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		_, err = tx.Exec(insertTag, tag)
+		if err != nil {
+			return err
+		}
+
+		stmt := `INSERT INTO snippet_tags (snippet_id, tag_id) SELECT ?, id FROM tags WHERE name = ?`
+		_, err = tx.Exec(stmt, snippetID, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachTags loads the tags of all given snippets, sorted by name
+func attachTags(db queryer, snippets []*Snippet) error {
+	if len(snippets) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*Snippet, len(snippets))
+	args := make([]any, len(snippets))
+	for i, s := range snippets {
+		s.Tags = []string{}
+		byID[s.ID] = s
+		args[i] = s.ID
+	}
+
+	stmt := `SELECT st.snippet_id, t.name FROM snippet_tags st
+		JOIN tags t ON t.id = st.tag_id
+		WHERE st.snippet_id IN (?` + strings.Repeat(", ?", len(snippets)-1) + `)
+		ORDER BY t.name`
+
+	rows, err := db.Query(stmt, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var name string
+		err = rows.Scan(&id, &name)
+		if err != nil {
+			return err
+		}
+		byID[id].Tags = append(byID[id].Tags, name)
+	}
+
+	return rows.Err()
+}
+
+// snippetsByTag returns the unexpired snippets with the tag, newest first.
+// Only public snippets and the user's own are included. now is the SQL
+// expression for the current time.
+func snippetsByTag(db *sql.DB, now string, tag string, userID int, limit, offset int) ([]*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+		WHERE id IN (SELECT st.snippet_id FROM snippet_tags st
+				JOIN tags t ON t.id = st.tag_id WHERE t.name = ?)
+			AND expires > ` + now + ` AND (visibility = 'public' OR user_id = ?)
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?`
+
+	return querySnippets(db, stmt, tag, userID, limit, offset)
+}
+
+// popularTags returns up to limit tags used by the most unexpired public
+// snippets. now is the SQL expression for the current time.
+func popularTags(db *sql.DB, now string, limit int) ([]*TagCount, error) {
+	stmt := `SELECT t.name, COUNT(*) FROM tags t
+		JOIN snippet_tags st ON st.tag_id = t.id
+		JOIN snippets s ON s.id = st.snippet_id
+		WHERE s.expires > ` + now + ` AND s.visibility = 'public'
+		GROUP BY t.id, t.name
+		ORDER BY COUNT(*) DESC, t.name
+		LIMIT ?`
+
+	rows, err := db.Query(stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*TagCount{}
+	for rows.Next() {
+		t := &TagCount{}
+		err = rows.Scan(&t.Name, &t.Count)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// ByTag returns the unexpired snippets with the tag, newest first. Only
+// public snippets and the user's own are included.
+func (m *SnippetModel) ByTag(tag string, userID int, limit, offset int) ([]*Snippet, error) {
+	return snippetsByTag(m.DB, "UTC_TIMESTAMP()", tag, userID, limit, offset)
+}
+
+// PopularTags returns up to limit tags used by the most public snippets
+func (m *SnippetModel) PopularTags(limit int) ([]*TagCount, error) {
+	return popularTags(m.DB, "UTC_TIMESTAMP()", limit)
+}
+
+// ByTag returns the unexpired snippets with the tag, newest first. Only
+// public snippets and the user's own are included.
+func (m *SQLiteSnippetModel) ByTag(tag string, userID int, limit, offset int) ([]*Snippet, error) {
+	return snippetsByTag(m.DB, "datetime('now')", tag, userID, limit, offset)
+}
+
+// PopularTags returns up to limit tags used by the most public snippets
+func (m *SQLiteSnippetModel) PopularTags(limit int) ([]*TagCount, error) {
+	return popularTags(m.DB, "datetime('now')", limit)
+}
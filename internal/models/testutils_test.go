@@ -0,0 +1,65 @@
+package models
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"snippet.devlake.xyz/internal/migrations"
+
+	_ "modernc.org/sqlite"
+)
+
+// newTestDB opens a fresh SQLite database in a temporary directory with
+// all migrations applied. The database is closed and removed again
+// when the test finishes.
+func newTestDB(t *testing.T) *sql.DB {
+	// same options as the application uses, concurrent writers wait for each
+	// other like they do there
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") +
+		"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate&_time_format=sqlite"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := migrations.New(db, "sqlite")
+	if err != nil {
+		db.Close()
+		t.Fatal(err)
+	}
+
+	_, err = migrator.Up()
+	if err != nil {
+		db.Close()
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	return db
+}
+
+// daysFromNow returns the time n days from now, to the second
+func daysFromNow(n int) time.Time {
+	return time.Now().UTC().Truncate(time.Second).AddDate(0, 0, n)
+}
+
+// insertSnippet creates a public snippet expiring after the given number of
+// days and returns its numeric and short IDs
+func insertSnippet(t *testing.T, m *SQLiteSnippetModel, title string, expires int, userID int) (int, string) {
+	shortID, err := m.Insert(SnippetInput{Title: title, Content: "content of " + title, Visibility: VisibilityPublic, Expires: daysFromNow(expires)}, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var id int
+	err = m.DB.QueryRow(`SELECT id FROM snippets WHERE short_id = ?`, shortID).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id, shortID
+}
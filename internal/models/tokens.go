@@ -0,0 +1,162 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// tokenPrefix starts every API token, which makes leaked tokens easy to
+// recognise and tells them apart from other credentials
+const tokenPrefix = "sbt_"
+
+// Token is a personal API token. Only a hash of the token is stored, the
+// plain text is returned once when it's created.
+type Token struct {
+	Created  time.Time
+	LastUsed time.Time // zero if the token was never used
+	Name     string
+	ID       int
+	UserID   int
+}
+
+// TokenModelInterface describes the API token storage operations
+type TokenModelInterface interface {
+	Insert(userID int, name string) (string, error)
+	ByUser(userID int) ([]*Token, error)
+	Revoke(id, userID int) error
+	Authenticate(token string) (int, error)
+}
+
+// newToken returns a random token and the hash to store for it
+func newToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	_, err = rand.Read(buf)
+	if err != nil {
+		return "", "", err
+	}
+
+	token = tokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+// hashToken returns the hex encoded SHA-256 hash of token. Tokens are long
+// and random, so unlike passwords they don't need a slow hash.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+const tokenColumns = `id, user_id, name, created, last_used`
+
+// queryTokens returns all tokens of a user, newest first
+func queryTokens(db *sql.DB, userID int) ([]*Token, error) {
+	stmt := `SELECT ` + tokenColumns + ` FROM api_tokens
+		WHERE user_id = ? ORDER BY id DESC`
+
+	rows, err := db.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*Token{}
+
+	for rows.Next() {
+		t := &Token{}
+		var lastUsed sql.NullTime
+		err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Created, &lastUsed)
+		if err != nil {
+			return nil, err
+		}
+		t.LastUsed = lastUsed.Time
+
+		tokens = append(tokens, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// revokeToken deletes a token, but only if it belongs to the user
+func revokeToken(db *sql.DB, id, userID int) error {
+	result, err := db.Exec(`DELETE FROM api_tokens WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+// authenticateToken looks up the owner of token and records the time it
+// was used, now is the SQL expression for the current time
+func authenticateToken(db *sql.DB, token, now string) (int, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return 0, ErrInvalidCredentials
+	}
+	hash := hashToken(token)
+
+	var id, userID int
+	err := db.QueryRow(`SELECT id, user_id FROM api_tokens WHERE token_hash = ?`, hash).Scan(&id, &userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidCredentials
+		} else {
+			return 0, err
+		}
+	}
+
+	_, err = db.Exec(`UPDATE api_tokens SET last_used = `+now+` WHERE id = ?`, id)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// TokenModel is the MySQL implementation of TokenModelInterface
+type TokenModel struct {
+	DB *sql.DB
+}
+
+// Insert creates a new named token for the user and returns it in plain text
+func (m *TokenModel) Insert(userID int, name string) (string, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	stmt := `INSERT INTO api_tokens (user_id, name, token_hash, created)
+		VALUES(?, ?, ?, UTC_TIMESTAMP())`
+
+	_, err = m.DB.Exec(stmt, userID, name, hash)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (m *TokenModel) ByUser(userID int) ([]*Token, error) {
+	return queryTokens(m.DB, userID)
+}
+
+// Revoke deletes the token if it belongs to the user, ErrNoRecord is
+// returned otherwise
+func (m *TokenModel) Revoke(id, userID int) error {
+	return revokeToken(m.DB, id, userID)
+}
+
+// Authenticate returns the ID of the user owning token, or
+// ErrInvalidCredentials if there is no such token
+func (m *TokenModel) Authenticate(token string) (int, error) {
+	return authenticateToken(m.DB, token, "UTC_TIMESTAMP()")
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// SQLiteTokenModel is the SQLite implementation of TokenModelInterface
+type SQLiteTokenModel struct {
+	DB *sql.DB
+}
+
+// Insert creates a new named token for the user and returns it in plain text
+func (m *SQLiteTokenModel) Insert(userID int, name string) (string, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	stmt := `INSERT INTO api_tokens (user_id, name, token_hash, created)
+		VALUES(?, ?, ?, datetime('now'))`
+
+	_, err = m.DB.Exec(stmt, userID, name, hash)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (m *SQLiteTokenModel) ByUser(userID int) ([]*Token, error) {
+	return queryTokens(m.DB, userID)
+}
+
+// Revoke deletes the token if it belongs to the user, ErrNoRecord is
+// returned otherwise
+func (m *SQLiteTokenModel) Revoke(id, userID int) error {
+	return revokeToken(m.DB, id, userID)
+}
+
+// Authenticate returns the ID of the user owning token, or
+// ErrInvalidCredentials if there is no such token
+func (m *SQLiteTokenModel) Authenticate(token string) (int, error) {
+	return authenticateToken(m.DB, token, "datetime('now')")
+}
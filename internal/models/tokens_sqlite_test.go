@@ -0,0 +1,69 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"snippet.devlake.xyz/internal/assert"
+)
+
+func TestSQLiteTokenModel(t *testing.T) {
+	db := newTestDB(t)
+	users := SQLiteUserModel{DB: db}
+	m := SQLiteTokenModel{DB: db}
+
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		err := users.Insert("User", email, "pa$$word")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	token, err := m.Insert(1, "laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, strings.HasPrefix(token, tokenPrefix), true)
+
+	// only the hash is stored
+	var stored string
+	err = db.QueryRow(`SELECT token_hash FROM api_tokens`).Scan(&stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, stored, hashToken(token))
+
+	tokens, err := m.ByUser(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(tokens), 1)
+	assert.Equal(t, tokens[0].Name, "laptop")
+	assert.Equal(t, tokens[0].LastUsed.IsZero(), true)
+
+	userID, err := m.Authenticate(token)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, userID, 1)
+
+	tokens, err = m.ByUser(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, tokens[0].LastUsed.IsZero(), false)
+
+	for _, invalid := range []string{"", "sbt_unknown", strings.TrimPrefix(token, tokenPrefix)} {
+		_, err = m.Authenticate(invalid)
+		assert.Equal(t, errors.Is(err, ErrInvalidCredentials), true)
+	}
+
+	// other users can't revoke the token
+	err = m.Revoke(tokens[0].ID, 2)
+	assert.Equal(t, errors.Is(err, ErrNoRecord), true)
+
+	err = m.Revoke(tokens[0].ID, 1)
+	assert.Equal(t, err, nil)
+
+	_, err = m.Authenticate(token)
+	assert.Equal(t, errors.Is(err, ErrInvalidCredentials), true)
+}
@@ -18,6 +18,15 @@ type User struct {
 	ID             int
 }
 
+// UserModelInterface describes the user storage operations used by the
+// web application, so that the backing database can be swapped out.
+type UserModelInterface interface {
+	Insert(name, email, password string) error
+	Authenticate(email, password string) (int, error)
+	Exist(id int) (bool, error)
+}
+
+// UserModel is the MySQL implementation of UserModelInterface
 type UserModel struct {
 	DB *sql.DB
 }
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLiteUserModel is the SQLite implementation of UserModelInterface
+type SQLiteUserModel struct {
+	DB *sql.DB
+}
+
+func (m *SQLiteUserModel) Insert(name, email, password string) error {
+	// Hash user password using bcrypt
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO users (name, email, hashed_password, created) values(?, ?, ?, datetime('now'))`
+	_, err = m.DB.Exec(stmt, name, email, string(hashedPassword))
+	if err != nil {
+		// SQLite reports unique index violations by column, not by index name
+		var sqliteError *sqlite.Error
+		if errors.As(err, &sqliteError) {
+			if sqliteError.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE &&
+				strings.Contains(sqliteError.Error(), "users.email") {
+				return ErrDuplicateEmail
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (m *SQLiteUserModel) Authenticate(email, password string) (int, error) {
+	var id int
+	var hashedPassword []byte
+
+	// query and check if email exists
+	stmt := "select id, hashed_password from users where email = ?"
+	err := m.DB.QueryRow(stmt, email).Scan(&id, &hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidCredentials
+		} else {
+			return 0, err
+		}
+	}
+
+	// check hashed password
+	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return 0, ErrInvalidCredentials
+		} else {
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
+
+func (m *SQLiteUserModel) Exist(id int) (bool, error) {
+	var exists bool
+	stmt := "select EXISTS(select true from users where id = ?)"
+	err := m.DB.QueryRow(stmt, id).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"snippet.devlake.xyz/internal/assert"
+)
+
+func TestSQLiteUserModelInsert(t *testing.T) {
+	m := SQLiteUserModel{DB: newTestDB(t)}
+
+	err := m.Insert("Alice Jones", "alice@example.com", "pa$$word")
+	assert.Equal(t, err, nil)
+
+	// Inserting the same email a second time must be reported as a duplicate
+	err = m.Insert("Alice Smith", "alice@example.com", "pa$$word")
+	assert.Equal(t, errors.Is(err, ErrDuplicateEmail), true)
+}
+
+func TestSQLiteUserModelExists(t *testing.T) {
+	m := SQLiteUserModel{DB: newTestDB(t)}
+
+	err := m.Insert("Alice Jones", "alice@example.com", "pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		userID int
+		want   bool
+	}{
+		{
+			name:   "Valid ID",
+			userID: 1,
+			want:   true,
+		},
+		{
+			name:   "Zero ID",
+			userID: 0,
+			want:   false,
+		},
+		{
+			name:   "Non-existent ID",
+			userID: 2,
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exists, err := m.Exist(tt.userID)
+
+			assert.Equal(t, exists, tt.want)
+			assert.Equal(t, err, nil)
+		})
+	}
+}
@@ -7,14 +7,21 @@ import (
 )
 
 type Validator struct {
-	FieldErrors    map[string]string
-	NonFieldErrors []string
+	FieldErrors    map[string]string `json:"field_errors,omitempty"`
+	NonFieldErrors []string          `json:"non_field_errors,omitempty"`
 }
 
 var EmailRX = regexp.MustCompile(
 	"^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$",
 )
 
+// TagRX matches a single lowercase tag such as "go", "c++" or "node.js"
+var TagRX = regexp.MustCompile("^[a-z0-9][a-z0-9+.-]*$")
+
+// FileNameRX matches a plain file name such as "main.go" or "Makefile",
+// without directories or a leading dot
+var FileNameRX = regexp.MustCompile("^[A-Za-z0-9_-][A-Za-z0-9._-]*$")
+
 func (v *Validator) Valid() bool {
 	return len(v.FieldErrors) == 0 && len(v.NonFieldErrors) == 0
 }
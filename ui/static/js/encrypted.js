@@ -0,0 +1,139 @@
+// End-to-end encrypted snippets. Content is encrypted with AES-GCM in the
+// browser, the server only stores base64(nonce || ciphertext). The key is
+// kept in the URL fragment, which browsers never send to the server.
+
+function bytesToBase64(bytes) {
+	var binary = "";
+	for (var i = 0; i < bytes.length; i++) {
+		binary += String.fromCharCode(bytes[i]);
+	}
+	return btoa(binary);
+}
+
+function base64ToBytes(s) {
+	var binary = atob(s);
+	var bytes = new Uint8Array(binary.length);
+	for (var i = 0; i < binary.length; i++) {
+		bytes[i] = binary.charCodeAt(i);
+	}
+	return bytes;
+}
+
+// keys go into the fragment base64url encoded without padding
+function keyToFragment(bytes) {
+	return bytesToBase64(bytes).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+}
+
+function fragmentToKey(s) {
+	return base64ToBytes(s.replace(/-/g, "+").replace(/_/g, "/"));
+}
+
+async function encryptText(text) {
+	var key = await crypto.subtle.generateKey({name: "AES-GCM", length: 256}, true, ["encrypt"]);
+	var nonce = crypto.getRandomValues(new Uint8Array(12));
+	var ciphertext = await crypto.subtle.encrypt({name: "AES-GCM", iv: nonce}, key, new TextEncoder().encode(text));
+
+	var blob = new Uint8Array(nonce.length + ciphertext.byteLength);
+	blob.set(nonce);
+	blob.set(new Uint8Array(ciphertext), nonce.length);
+
+	var rawKey = new Uint8Array(await crypto.subtle.exportKey("raw", key));
+	return {content: bytesToBase64(blob), key: keyToFragment(rawKey)};
+}
+
+async function decryptText(content, fragment) {
+	var key = await crypto.subtle.importKey("raw", fragmentToKey(fragment), "AES-GCM", false, ["decrypt"]);
+	var blob = base64ToBytes(content);
+	var plaintext = await crypto.subtle.decrypt({name: "AES-GCM", iv: blob.slice(0, 12)}, key, blob.slice(12));
+	return new TextDecoder().decode(plaintext);
+}
+
+// apiErrorMessage turns a JSON API error response into a single line
+function apiErrorMessage(body) {
+	var messages = [];
+	for (var field in body.field_errors || {}) {
+		messages.push(field + ": " + body.field_errors[field]);
+	}
+	return messages.length > 0 ? messages.join(", ") : body.error;
+}
+
+(function () {
+	var createForm = document.querySelector("form.encrypted-create");
+	if (createForm && window.crypto && crypto.subtle) {
+		var submit = createForm.querySelector("input[type=submit]");
+		var errorBox = createForm.querySelector("div.error[hidden]");
+		submit.disabled = false;
+
+		createForm.addEventListener("submit", async function (event) {
+			event.preventDefault();
+			submit.disabled = true;
+			errorBox.hidden = true;
+
+			try {
+				var data = new FormData(createForm);
+				var encrypted = await encryptText(data.get("content"));
+
+				// custom expiry times are picked in UTC, like the other forms
+				var expires = data.get("expires");
+				if (expires === "custom") {
+					expires = data.get("expires_at");
+				}
+
+				var response = await fetch(createForm.action, {
+					method: "POST",
+					credentials: "same-origin",
+					headers: {"Content-Type": "application/json"},
+					body: JSON.stringify({
+						kind: "encrypted",
+						title: data.get("title"),
+						content: encrypted.content,
+						visibility: data.get("visibility"),
+						expires: expires,
+						burn_after_reading: data.get("burn") === "true",
+						comments_disabled: data.get("disable_comments") === "true",
+					}),
+				});
+				var body = await response.json();
+				if (!response.ok) {
+					throw new Error(apiErrorMessage(body));
+				}
+
+				window.location = "/snippet/view/" + body.snippet.id + "#" + encrypted.key;
+			} catch (err) {
+				errorBox.textContent = "Could not create the snippet: " + err.message;
+				errorBox.hidden = false;
+				submit.disabled = false;
+			}
+		});
+	}
+
+	var encryptedView = document.querySelector("div.encrypted[data-ciphertext]");
+	if (encryptedView) {
+		var statusLine = encryptedView.querySelector(".encrypted-status");
+		var output = encryptedView.querySelector("pre.decrypted");
+		var fragment = window.location.hash.slice(1);
+
+		if (!fragment) {
+			statusLine.textContent = "This snippet is encrypted and the link is missing its key.";
+		} else if (!window.crypto || !crypto.subtle) {
+			statusLine.textContent = "Your browser can't decrypt this snippet.";
+		} else {
+			decryptText(encryptedView.dataset.ciphertext, fragment).then(function (text) {
+				output.textContent = text;
+				output.hidden = false;
+				statusLine.hidden = true;
+			}, function () {
+				statusLine.textContent = "This snippet could not be decrypted, the key in the link is wrong.";
+			});
+		}
+	}
+
+	// forms leading to an encrypted snippet carry the key along, it lives in
+	// the fragment and isn't part of the form action otherwise
+	var keepFragment = document.querySelectorAll("form[data-keep-fragment]");
+	for (var i = 0; i < keepFragment.length; i++) {
+		if (window.location.hash) {
+			keepFragment[i].action += window.location.hash;
+		}
+	}
+})();
@@ -0,0 +1,26 @@
+// Add and remove controls for the files of a snippet. New files get the
+// next unused index, gaps left by removed files are ignored by the server.
+(function () {
+	var files = document.querySelector("div.files[data-next-index]");
+	if (!files) {
+		return;
+	}
+
+	var template = files.querySelector("template");
+	var addButton = files.querySelector("button.add-file");
+	var nextIndex = parseInt(files.dataset.nextIndex, 10);
+
+	addButton.hidden = false;
+	addButton.addEventListener("click", function () {
+		var html = template.innerHTML.replace(/files\[INDEX\]/g, "files[" + nextIndex + "]");
+		nextIndex++;
+		addButton.insertAdjacentHTML("beforebegin", html);
+		addButton.previousElementSibling.querySelector("input").focus();
+	});
+
+	files.addEventListener("click", function (event) {
+		if (event.target.matches("button.remove-file")) {
+			event.target.closest("fieldset.file").remove();
+		}
+	});
+})();